@@ -254,6 +254,15 @@ func worker(inst config.Instance) {
 		return
 	}
 
+	// Sources keep per-instance state keyed by their params; the name keeps
+	// two instances with the same params apart
+	params := make(map[string]string, len(inst.Params)+1)
+	for k, v := range inst.Params {
+		params[k] = v
+	}
+	params[collectors.InstanceParam] = inst.Name
+	collectors.Start(inst.Source, params)
+
 	log.Printf("%s Started (%s mode) -> %s", prefix, def.Mode, url)
 
 	ticker := time.NewTicker(time.Duration(inst.Interval) * time.Second)
@@ -262,7 +271,7 @@ func worker(inst config.Instance) {
 	for {
 		<-ticker.C
 
-		shipResults, err := col(inst.Params)
+		shipResults, err := col(params)
 		if err != nil {
 			log.Printf("%s ❌ Collection Failed: %v", prefix, err)
 			status.Update(inst.Name, err)
@@ -0,0 +1,121 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// DNSCollector resolves each target name and reports resolver latency,
+// answer count and (optionally) whether the expected answer was returned.
+//
+//	--param targets=example.com,api.example.com
+//	--param record_type=A            (A, AAAA, CNAME, MX, TXT, NS, PTR)
+//	--param resolver=1.1.1.1:53      (default: system resolver)
+//	--param expect=93.184.216.34     (answer must contain this)
+func DNSCollector(params map[string]string) ([]map[string]interface{}, error) {
+	timeout := paramMillis(params, "timeout_ms", 5*time.Second)
+	recordType := strings.ToUpper(params["record_type"])
+	if recordType == "" {
+		recordType = "A"
+	}
+	expect := strings.ToLower(strings.TrimSuffix(params["expect"], "."))
+
+	resolver := net.DefaultResolver
+	if server := params["resolver"]; server != "" {
+		resolver = customResolver(server)
+	}
+
+	return probeTargets(params, "dns", "dns_up", func(target string) map[string]interface{} {
+		result := map[string]interface{}{
+			"dns_up":         int64(0),
+			"dns_latency_ms": int64(0),
+			"dns_answers":    int64(0),
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		start := time.Now()
+		answers, err := dnsLookup(ctx, resolver, recordType, target)
+		result["dns_latency_ms"] = time.Since(start).Milliseconds()
+		if err != nil {
+			if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+				result["dns_nxdomain"] = int64(1)
+			}
+			return result
+		}
+
+		result["dns_up"] = boolToInt(len(answers) > 0)
+		result["dns_answers"] = int64(len(answers))
+
+		if expect != "" {
+			found := false
+			for _, a := range answers {
+				if strings.ToLower(strings.TrimSuffix(a, ".")) == expect {
+					found = true
+					break
+				}
+			}
+			result["dns_expect_ok"] = boolToInt(found)
+		}
+		return result
+	})
+}
+
+// customResolver sends every query to server ("1.1.1.1" or "10.0.0.2:5353").
+func customResolver(server string) *net.Resolver {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+func dnsLookup(ctx context.Context, r *net.Resolver, recordType, name string) ([]string, error) {
+	var out []string
+
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := r.LookupIP(ctx, network, name)
+		for _, ip := range ips {
+			out = append(out, ip.String())
+		}
+		return out, err
+	case "CNAME":
+		cname, err := r.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		return []string{cname}, nil
+	case "MX":
+		mxs, err := r.LookupMX(ctx, name)
+		for _, mx := range mxs {
+			out = append(out, mx.Host)
+		}
+		return out, err
+	case "NS":
+		nss, err := r.LookupNS(ctx, name)
+		for _, ns := range nss {
+			out = append(out, ns.Host)
+		}
+		return out, err
+	case "TXT":
+		return r.LookupTXT(ctx, name)
+	case "PTR":
+		return r.LookupAddr(ctx, name)
+	default:
+		return nil, fmt.Errorf("unsupported record_type %q", recordType)
+	}
+}
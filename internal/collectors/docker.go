@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
 )
 
@@ -106,11 +107,6 @@ func DockerCollector(params map[string]string) ([]map[string]interface{}, error)
 	}
 
 	// Helpers
-	boolToInt := func(b bool) int64 {
-		if b { return 1 }
-		return 0
-	}
-
 	swarmStateToInt := func(s string) int64 {
 		switch strings.ToLower(s) {
 		case "inactive": return 0
@@ -121,43 +117,61 @@ func DockerCollector(params map[string]string) ([]map[string]interface{}, error)
 		default: return -1
 		}
 	}
-	
-	
-	volumeCount := int64(len(info.Plugins.Volume))
-
-	return []map[string]interface{}{
-		{
-			// --- OLD COMPATIBILITY KEYS ---
-			"docker_containers_total":      int64(len(containers)),
-			"docker_containers_running":    running,
-			"docker_containers_paused":     paused,
-			"docker_containers_exited":     exited,
-			"docker_images_total":          int64(info.Images),
-			"docker_volumes_total":         volumeCount,
-			"docker_goroutines":            int64(info.NGoroutines),
-
-			// Granular States
-			"docker_containers_created":    created,
-			"docker_containers_restarting": restarting,
-			"docker_containers_removing":   removing,
-			"docker_containers_dead":       dead,
-
-			// System Resources
-			"docker_ncpu":             int64(info.NCPU),
-			"docker_mem_total_bytes":  int64(info.MemTotal),
-			"docker_file_descriptors": int64(info.NFd),
-			"docker_events_listeners": int64(info.NEventsListener),
-			
-			// Swarm Info
-			"docker_swarm_state":          swarmStateToInt(string(info.Swarm.LocalNodeState)),
-			"docker_swarm_managers":       int64(info.Swarm.Managers),
-			"docker_swarm_nodes":          int64(info.Swarm.Nodes),
-
-			// Capabilities
-			"docker_cap_swap_limit":       boolToInt(info.SwapLimit),
-			"docker_cap_memory_limit":     boolToInt(info.MemoryLimit),
-			"docker_cap_oom_kill_disable": boolToInt(info.OomKillDisable),
-			"docker_cap_ipv4_forwarding":  boolToInt(info.IPv4Forwarding),
-		},
-	}, nil
+
+	// info.Plugins.Volume lists volume *drivers*, so ask for the real volumes.
+	// -1 signals the list call failed rather than "zero volumes".
+	volumeCount := int64(-1)
+	if vols, err := cli.VolumeList(ctx, volume.ListOptions{}); err == nil {
+		volumeCount = int64(len(vols.Volumes))
+	}
+
+	engine := map[string]interface{}{
+		// --- OLD COMPATIBILITY KEYS ---
+		"docker_containers_total":      int64(len(containers)),
+		"docker_containers_running":    running,
+		"docker_containers_paused":     paused,
+		"docker_containers_exited":     exited,
+		"docker_images_total":          int64(info.Images),
+		"docker_volumes_total":         volumeCount,
+		"docker_goroutines":            int64(info.NGoroutines),
+
+		// Granular States
+		"docker_containers_created":    created,
+		"docker_containers_restarting": restarting,
+		"docker_containers_removing":   removing,
+		"docker_containers_dead":       dead,
+
+		// System Resources
+		"docker_ncpu":             int64(info.NCPU),
+		"docker_mem_total_bytes":  int64(info.MemTotal),
+		"docker_file_descriptors": int64(info.NFd),
+		"docker_events_listeners": int64(info.NEventsListener),
+		
+		// Swarm Info
+		"docker_swarm_state":          swarmStateToInt(string(info.Swarm.LocalNodeState)),
+		"docker_swarm_managers":       int64(info.Swarm.Managers),
+		"docker_swarm_nodes":          int64(info.Swarm.Nodes),
+
+		// Capabilities
+		"docker_cap_swap_limit":       boolToInt(info.SwapLimit),
+		"docker_cap_memory_limit":     boolToInt(info.MemoryLimit),
+		"docker_cap_oom_kill_disable": boolToInt(info.OomKillDisable),
+		"docker_cap_ipv4_forwarding":  boolToInt(info.IPv4Forwarding),
+	}
+
+	// Optional disk usage breakdown (--param disk_usage=true), refreshed on its own schedule
+	if paramBool(params, "disk_usage") {
+		for k, v := range dockerDiskUsage(params) {
+			engine[k] = v
+		}
+	}
+
+	// 5. Optional per-container metrics (--param per_container=true)
+	// One ship per container by default, or labeled cargo with container_mode=cargo.
+	if paramBool(params, "per_container") {
+		perContainer := collectContainerMetrics(ctx, cli, containers, newContainerFilter(params))
+		return fanOut(params, "container_mode", engine, perContainer), nil
+	}
+
+	return []map[string]interface{}{engine}, nil
 }
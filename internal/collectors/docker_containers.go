@@ -0,0 +1,231 @@
+package collectors
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// Limits how many containers we query in parallel so large hosts
+// don't flood the daemon with stats requests.
+const dockerStatsConcurrency = 8
+
+// containerFilter decides which containers get per-container metrics.
+// Every configured list must match for a container to be included.
+type containerFilter struct {
+	names        []string // glob patterns, e.g. "web-*"
+	excludeNames []string
+	images       []string // glob patterns, e.g. "nginx*"
+	labels       map[string]string
+}
+
+func newContainerFilter(params map[string]string) containerFilter {
+	f := containerFilter{
+		names:        splitList(params["container_names"]),
+		excludeNames: splitList(params["exclude_names"]),
+		images:       splitList(params["container_images"]),
+		labels:       make(map[string]string),
+	}
+	// container_labels="com.example.team=ops,monitor" ("key" alone means "key exists")
+	for _, l := range splitList(params["container_labels"]) {
+		k, v, _ := strings.Cut(l, "=")
+		f.labels[k] = v
+	}
+	return f
+}
+
+func (f containerFilter) match(name string, c types.Container) bool {
+	if len(f.names) > 0 && !globAny(f.names, name) {
+		return false
+	}
+	if globAny(f.excludeNames, name) {
+		return false
+	}
+	if len(f.images) > 0 && !globAny(f.images, c.Image) {
+		return false
+	}
+	for k, v := range f.labels {
+		got, ok := c.Labels[k]
+		if !ok || (v != "" && got != v) {
+			return false
+		}
+	}
+	return true
+}
+
+func globAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// containerName returns the primary name without Docker's leading slash.
+func containerName(c types.Container) string {
+	if len(c.Names) > 0 {
+		return strings.TrimPrefix(c.Names[0], "/")
+	}
+	if len(c.ID) > 12 {
+		return c.ID[:12]
+	}
+	return c.ID
+}
+
+// collectContainerMetrics gathers stats for every container that passes the filter.
+// It returns one map per container, keyed by container name.
+func collectContainerMetrics(ctx context.Context, cli *client.Client, containers []types.Container, f containerFilter) map[string]map[string]interface{} {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]map[string]interface{})
+		sem     = make(chan struct{}, dockerStatsConcurrency)
+	)
+
+	for _, c := range containers {
+		name := containerName(c)
+		if !f.match(name, c) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(c types.Container, name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			m := containerMetrics(ctx, cli, c)
+			mu.Lock()
+			results[name] = m
+			mu.Unlock()
+		}(c, name)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// containerMetrics inspects one container and, if it is running, samples its stats.
+func containerMetrics(ctx context.Context, cli *client.Client, c types.Container) map[string]interface{} {
+	m := map[string]interface{}{
+		"docker_container_running": int64(0),
+	}
+	if c.State == "running" {
+		m["docker_container_running"] = int64(1)
+	}
+
+	// Inspect gives us restart count, health and start time
+	if info, err := cli.ContainerInspect(ctx, c.ID); err == nil && info.ContainerJSONBase != nil {
+		m["docker_container_restart_count"] = int64(info.RestartCount)
+		if info.State != nil {
+			m["docker_container_exit_code"] = int64(info.State.ExitCode)
+			m["docker_container_oom_killed"] = boolToInt(info.State.OOMKilled)
+			m["docker_container_health"] = healthToInt(info.State.Health)
+
+			if info.State.Running {
+				if started, err := time.Parse(time.RFC3339Nano, info.State.StartedAt); err == nil {
+					m["docker_container_uptime_seconds"] = int64(time.Since(started).Seconds())
+				}
+			}
+		}
+	}
+
+	if c.State != "running" {
+		return m
+	}
+
+	// Non-streaming stats: the daemon samples twice (~1s apart) so precpu is populated
+	statsCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := cli.ContainerStats(statsCtx, c.ID, false)
+	if err != nil {
+		m["docker_container_stats_error"] = int64(1)
+		return m
+	}
+	defer resp.Body.Close()
+
+	var s types.StatsJSON
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		m["docker_container_stats_error"] = int64(1)
+		return m
+	}
+
+	m["docker_container_cpu_percent"] = containerCPUPercent(s)
+
+	// Match `docker stats`: exclude reclaimable page cache from usage
+	memUsage := s.MemoryStats.Usage
+	if v, ok := s.MemoryStats.Stats["total_inactive_file"]; ok && v < memUsage {
+		memUsage -= v
+	} else if v, ok := s.MemoryStats.Stats["inactive_file"]; ok && v < memUsage {
+		memUsage -= v
+	}
+	m["docker_container_mem_usage_bytes"] = int64(memUsage)
+	m["docker_container_mem_limit_bytes"] = int64(s.MemoryStats.Limit)
+	if s.MemoryStats.Limit > 0 {
+		m["docker_container_mem_percent"] = float64(memUsage) / float64(s.MemoryStats.Limit) * 100
+	}
+
+	var rx, tx uint64
+	for _, n := range s.Networks {
+		rx += n.RxBytes
+		tx += n.TxBytes
+	}
+	m["docker_container_net_rx_bytes"] = int64(rx)
+	m["docker_container_net_tx_bytes"] = int64(tx)
+
+	var blkRead, blkWrite uint64
+	for _, e := range s.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			blkRead += e.Value
+		case "write":
+			blkWrite += e.Value
+		}
+	}
+	m["docker_container_block_read_bytes"] = int64(blkRead)
+	m["docker_container_block_write_bytes"] = int64(blkWrite)
+	m["docker_container_pids"] = int64(s.PidsStats.Current)
+
+	return m
+}
+
+// containerCPUPercent uses the same formula as the docker CLI.
+func containerCPUPercent(s types.StatsJSON) float64 {
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	sysDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+
+	cpus := float64(s.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+
+	if cpuDelta <= 0 || sysDelta <= 0 || cpus == 0 {
+		return 0
+	}
+	return cpuDelta / sysDelta * cpus * 100
+}
+
+// healthToInt: 1 healthy, 0 unhealthy, 2 starting, -1 no healthcheck configured.
+func healthToInt(h *types.Health) int64 {
+	if h == nil {
+		return -1
+	}
+	switch h.Status {
+	case types.Healthy:
+		return 1
+	case types.Unhealthy:
+		return 0
+	case types.Starting:
+		return 2
+	default:
+		return -1
+	}
+}
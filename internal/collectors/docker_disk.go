@@ -0,0 +1,140 @@
+package collectors
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+// The system df endpoint walks every layer and volume on disk, which can take
+// tens of seconds on busy hosts. We refresh it in the background on its own
+// schedule and hand out the last result on every tick.
+var (
+	dockerDiskMu       sync.Mutex
+	dockerDiskCache    map[string]interface{}
+	dockerDiskUpdated  time.Time // last successful refresh
+	dockerDiskFailed   time.Time // last failed one
+	dockerDiskRunning  bool
+	dockerDiskInterval = 15 * time.Minute
+	// A failed refresh is retried after this, not a full interval
+	dockerDiskRetry = time.Minute
+)
+
+// dockerDiskUsage returns the cached disk usage breakdown and triggers a
+// refresh when it is older than the configured interval.
+func dockerDiskUsage(params map[string]string) map[string]interface{} {
+	interval := dockerDiskInterval
+	if s := paramInt(params, "disk_usage_interval_s", 0); s > 0 {
+		interval = time.Duration(s) * time.Second
+	}
+
+	dockerDiskMu.Lock()
+	defer dockerDiskMu.Unlock()
+
+	due := time.Since(dockerDiskUpdated) >= interval && time.Since(dockerDiskFailed) >= min(dockerDiskRetry, interval)
+	if !dockerDiskRunning && due {
+		dockerDiskRunning = true
+		go refreshDockerDiskUsage()
+	}
+	return dockerDiskCache
+}
+
+func refreshDockerDiskUsage() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	var result map[string]interface{}
+	if cli, err := getDockerClient(); err == nil {
+		du, err := cli.DiskUsage(ctx, types.DiskUsageOptions{})
+		if err != nil {
+			log.Printf("⚠️ Docker disk usage failed: %v", err)
+		} else {
+			result = summarizeDiskUsage(du)
+		}
+	}
+
+	dockerDiskMu.Lock()
+	defer dockerDiskMu.Unlock()
+	dockerDiskRunning = false
+	if result == nil {
+		dockerDiskFailed = time.Now()
+		return
+	}
+	dockerDiskUpdated = time.Now()
+	dockerDiskCache = result
+}
+
+func summarizeDiskUsage(du types.DiskUsage) map[string]interface{} {
+	var imgReclaimable, dangling int64
+	for _, img := range du.Images {
+		if img == nil {
+			continue
+		}
+		if img.Containers == 0 {
+			imgReclaimable += img.Size - max(img.SharedSize, 0)
+		}
+		if isDanglingImage(img.RepoTags) {
+			dangling++
+		}
+	}
+
+	var ctrBytes int64
+	for _, c := range du.Containers {
+		if c != nil {
+			ctrBytes += c.SizeRw
+		}
+	}
+
+	var volBytes, volReclaimable, volUnused int64
+	for _, v := range du.Volumes {
+		if v == nil || v.UsageData == nil {
+			continue
+		}
+		// -1 means the daemon couldn't compute it
+		if v.UsageData.Size > 0 {
+			volBytes += v.UsageData.Size
+		}
+		if v.UsageData.RefCount == 0 {
+			volUnused++
+			if v.UsageData.Size > 0 {
+				volReclaimable += v.UsageData.Size
+			}
+		}
+	}
+
+	var cacheBytes, cacheReclaimable int64
+	for _, bc := range du.BuildCache {
+		if bc == nil {
+			continue
+		}
+		cacheBytes += bc.Size
+		if !bc.InUse && !bc.Shared {
+			cacheReclaimable += bc.Size
+		}
+	}
+
+	return map[string]interface{}{
+		"docker_df_images_bytes":                  du.LayersSize,
+		"docker_df_images_reclaimable_bytes":      imgReclaimable,
+		"docker_df_images_dangling":               dangling,
+		"docker_df_containers_bytes":              ctrBytes,
+		"docker_df_volumes_bytes":                 volBytes,
+		"docker_df_volumes_reclaimable_bytes":     volReclaimable,
+		"docker_df_volumes_unused":                volUnused,
+		"docker_df_build_cache_bytes":             cacheBytes,
+		"docker_df_build_cache_reclaimable_bytes": cacheReclaimable,
+	}
+}
+
+// isDanglingImage matches the docker CLI's notion of an untagged image.
+func isDanglingImage(tags []string) bool {
+	for _, t := range tags {
+		if t != "<none>:<none>" {
+			return false
+		}
+	}
+	return true
+}
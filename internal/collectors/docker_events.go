@@ -0,0 +1,289 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// dockerEventCounts holds what happened to one container since the last collection.
+type dockerEventCounts struct {
+	die, oom, restart, unhealthy, healthy int64
+	lastExitCode                          int64
+	image                                 string
+	labels                                map[string]string
+}
+
+// dockerLifecycle is the recent lifecycle of one container, used to tell
+// restart-policy restarts from manual stop/start.
+type dockerLifecycle struct {
+	last         events.Action
+	manual       bool // kill/stop seen since the last start
+	startCounted bool // the last start was already counted as a restart
+	at           time.Time
+}
+
+// dockerEventWatcher keeps a single subscription to the daemon's event stream
+// and accumulates counts between collector ticks, separately for every
+// instance so each one sees all events since its own last tick.
+type dockerEventWatcher struct {
+	mu         sync.Mutex
+	sinks      map[string]map[string]*dockerEventCounts // instance -> container name -> counts
+	lifecycle  map[string]*dockerLifecycle              // keyed by container name
+	connected  bool
+	reconnects int64
+}
+
+// Lifecycle state of containers that went quiet is dropped after this long
+const dockerLifecycleTTL = 24 * time.Hour
+
+var (
+	dockerEvents     *dockerEventWatcher
+	dockerEventsOnce sync.Once
+)
+
+// DockerEventsCollector reports container die/oom/health/restart events
+// that happened since the previous tick.
+func DockerEventsCollector(params map[string]string) ([]map[string]interface{}, error) {
+	counts, connected, reconnects := dockerEvents.drain(startDockerEvents(params))
+	f := newContainerFilter(params)
+
+	summary := map[string]interface{}{
+		"docker_events_connected":  boolToInt(connected),
+		"docker_events_reconnects": reconnects,
+	}
+	var dieTotal, oomTotal, restartTotal, unhealthyTotal int64
+
+	perContainer := make(map[string]map[string]interface{})
+
+	for name, c := range counts {
+		if !f.match(name, types.Container{Image: c.image, Labels: c.labels}) {
+			continue
+		}
+		dieTotal += c.die
+		oomTotal += c.oom
+		restartTotal += c.restart
+		unhealthyTotal += c.unhealthy
+
+		m := map[string]interface{}{
+			"docker_event_die":       c.die,
+			"docker_event_oom":       c.oom,
+			"docker_event_restart":   c.restart,
+			"docker_event_unhealthy": c.unhealthy,
+			"docker_event_healthy":   c.healthy,
+		}
+		if c.die > 0 {
+			m["docker_event_last_exit_code"] = c.lastExitCode
+		}
+
+		perContainer[name] = m
+	}
+
+	summary["docker_events_die_total"] = dieTotal
+	summary["docker_events_oom_total"] = oomTotal
+	summary["docker_events_restart_total"] = restartTotal
+	summary["docker_events_unhealthy_total"] = unhealthyTotal
+
+	return fanOut(params, "container_mode", summary, perContainer), nil
+}
+
+// startDockerEvents starts the shared stream (once) and registers the
+// instance, which counts events from then on. Returns the instance's key.
+func startDockerEvents(params map[string]string) string {
+	dockerEventsOnce.Do(func() {
+		dockerEvents = &dockerEventWatcher{
+			sinks:     make(map[string]map[string]*dockerEventCounts),
+			lifecycle: make(map[string]*dockerLifecycle),
+		}
+		go dockerEvents.run()
+	})
+
+	key := paramsHash(params)
+	dockerEvents.mu.Lock()
+	if dockerEvents.sinks[key] == nil {
+		dockerEvents.sinks[key] = make(map[string]*dockerEventCounts)
+	}
+	dockerEvents.mu.Unlock()
+	return key
+}
+
+// drain returns the counts accumulated for one instance and resets them for
+// its next interval.
+func (w *dockerEventWatcher) drain(instance string) (map[string]*dockerEventCounts, bool, int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	counts := w.sinks[instance]
+	w.sinks[instance] = make(map[string]*dockerEventCounts)
+
+	for name, l := range w.lifecycle {
+		if time.Since(l.at) > dockerLifecycleTTL {
+			delete(w.lifecycle, name)
+		}
+	}
+	return counts, w.connected, w.reconnects
+}
+
+// run subscribes forever, reconnecting with backoff when the daemon goes away.
+// Reconnects resume from the last seen event so nothing is lost across a restart.
+func (w *dockerEventWatcher) run() {
+	backoff := time.Second
+	since := time.Now()
+
+	for {
+		cli, err := getDockerClient()
+		if err != nil {
+			w.setConnected(false)
+			time.Sleep(backoff)
+			backoff = min(backoff*2, 30*time.Second)
+			continue
+		}
+
+		args := filters.NewArgs(
+			filters.Arg("type", string(events.ContainerEventType)),
+			filters.Arg("event", string(events.ActionDie)),
+			filters.Arg("event", string(events.ActionOOM)),
+			filters.Arg("event", string(events.ActionRestart)),
+			filters.Arg("event", string(events.ActionStart)),
+			filters.Arg("event", string(events.ActionStop)),
+			filters.Arg("event", string(events.ActionKill)),
+			filters.Arg("event", string(events.ActionDestroy)),
+			filters.Arg("event", string(events.ActionHealthStatus)),
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		msgs, errs := cli.Events(ctx, types.EventsOptions{
+			Since:   fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond()),
+			Filters: args,
+		})
+		w.setConnected(true)
+
+	stream:
+		for {
+			select {
+			case msg := <-msgs:
+				backoff = time.Second
+				if msg.TimeNano > 0 {
+					// +1ns so a reconnect doesn't replay (and double count) this event
+					since = time.Unix(0, msg.TimeNano+1)
+				}
+				w.record(msg)
+			case err := <-errs:
+				log.Printf("⚠️ Docker event stream lost: %v", err)
+				break stream
+			}
+		}
+		cancel()
+
+		// The daemon likely restarted; drop the cached client so we redial
+		dockerMu.Lock()
+		dockerCli = nil
+		dockerMu.Unlock()
+
+		w.mu.Lock()
+		w.connected = false
+		w.reconnects++
+		w.mu.Unlock()
+
+		time.Sleep(backoff)
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+func (w *dockerEventWatcher) setConnected(v bool) {
+	w.mu.Lock()
+	w.connected = v
+	w.mu.Unlock()
+}
+
+func (w *dockerEventWatcher) record(msg events.Message) {
+	name := msg.Actor.Attributes["name"]
+	if name == "" {
+		name = msg.Actor.ID
+	}
+	health := strings.HasPrefix(string(msg.Action), string(events.ActionHealthStatus))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if msg.Action == events.ActionDestroy {
+		delete(w.lifecycle, name)
+		return
+	}
+
+	// Work out what this event means once, then add it to every instance
+	var die, oom, restart, unhealthy, healthy int64
+	l := w.lifecycle[name]
+	if l == nil && !health {
+		l = &dockerLifecycle{}
+		w.lifecycle[name] = l
+	}
+
+	switch {
+	case msg.Action == events.ActionDie:
+		die = 1
+	case msg.Action == events.ActionOOM:
+		oom = 1
+	case msg.Action == events.ActionKill, msg.Action == events.ActionStop:
+		l.manual = true
+	case msg.Action == events.ActionStart:
+		// A restart policy shows up as die -> start with no kill/stop before it;
+		// a manual `docker stop` + `docker start` is not a restart
+		l.startCounted = l.last == events.ActionDie && !l.manual
+		if l.startCounted {
+			restart = 1
+		}
+		l.manual = false
+	case msg.Action == events.ActionRestart:
+		// `docker restart` emits kill -> die -> stop -> start -> restart
+		if !l.startCounted {
+			restart = 1
+		}
+		l.startCounted = false
+	case health:
+		if strings.HasSuffix(string(msg.Action), "unhealthy") {
+			unhealthy = 1
+		} else if strings.HasSuffix(string(msg.Action), "healthy") {
+			healthy = 1
+		}
+	}
+
+	// Health events happen constantly; only track lifecycle actions
+	if !health {
+		l.last = msg.Action
+		l.at = time.Now()
+	}
+
+	exitCode, hasExit := int64(0), false
+	if die > 0 {
+		if code, err := strconv.ParseInt(msg.Actor.Attributes["exitCode"], 10, 64); err == nil {
+			exitCode, hasExit = code, true
+		}
+	}
+
+	for _, counts := range w.sinks {
+		c, ok := counts[name]
+		if !ok {
+			c = &dockerEventCounts{}
+			counts[name] = c
+		}
+		c.image = msg.Actor.Attributes["image"]
+		c.labels = msg.Actor.Attributes
+		c.die += die
+		c.oom += oom
+		c.restart += restart
+		c.unhealthy += unhealthy
+		c.healthy += healthy
+		if hasExit {
+			c.lastExitCode = exitCode
+		}
+	}
+}
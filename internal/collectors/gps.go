@@ -0,0 +1,375 @@
+package collectors
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// gpsFix is the latest position assembled from NMEA sentences or gpsd reports.
+type gpsFix struct {
+	lat, lon, alt    float64
+	speedKmh, course float64
+	hdop             float64
+	quality, sats    int64 // GGA fix quality: 0 none, 1 GPS, 2 DGPS, 4 RTK fixed, 5 RTK float
+	hasAlt, hasMove  bool
+	sawGGA           bool
+	updated          time.Time // last position update
+}
+
+// gpsReader keeps one connection to a GPS source and tracks the latest fix.
+type gpsReader struct {
+	kind, addr string // "serial", "tcp" (raw NMEA) or "gpsd"
+	baud       int
+
+	mu        sync.Mutex
+	fix       gpsFix
+	connected bool
+}
+
+var (
+	gpsReadersMu sync.Mutex
+	gpsReaders   = make(map[string]*gpsReader) // keyed by kind + address
+)
+
+// GPSCollector reports the current position in the shape the gps harbor
+// type expects (latitude, longitude, altitude, speed, course, fix_quality,
+// satellites, hdop). Sources, in order of precedence:
+//
+//	--param device=/dev/ttyUSB0 --param baud=9600   (NMEA 0183 over serial)
+//	--param address=192.168.1.20:10110              (NMEA 0183 over TCP)
+//	--param gpsd=localhost:2947                     (default)
+//
+// Without a fix nothing is sent (no 0,0 positions), unless send_without_fix=true.
+func GPSCollector(params map[string]string) ([]map[string]interface{}, error) {
+	kind, addr := "gpsd", "localhost:2947"
+	switch {
+	case params["device"] != "":
+		kind, addr = "serial", params["device"]
+	case params["address"] != "":
+		kind, addr = "tcp", params["address"]
+	case params["gpsd"] != "":
+		addr = params["gpsd"]
+	}
+
+	r := getGPSReader(kind, addr, paramInt(params, "baud", 9600))
+	fix, connected := r.snapshot()
+
+	maxAge := time.Duration(paramInt(params, "max_age_s", 30)) * time.Second
+	valid := fix.quality > 0 && time.Since(fix.updated) <= maxAge
+
+	if !valid {
+		if !connected {
+			return nil, fmt.Errorf("gps %s %s not connected", kind, addr)
+		}
+		if !paramBool(params, "send_without_fix") {
+			return []map[string]interface{}{}, nil
+		}
+		return []map[string]interface{}{{
+			"fix_quality": int64(0),
+			"satellites":  fix.sats,
+		}}, nil
+	}
+
+	result := map[string]interface{}{
+		"latitude":    fix.lat,
+		"longitude":   fix.lon,
+		"fix_quality": fix.quality,
+		"satellites":  fix.sats,
+	}
+	if fix.hasAlt {
+		result["altitude"] = fix.alt
+	}
+	if fix.hasMove {
+		result["speed"] = fix.speedKmh
+		result["course"] = fix.course
+	}
+	if fix.hdop > 0 {
+		result["hdop"] = fix.hdop
+	}
+	return []map[string]interface{}{result}, nil
+}
+
+func getGPSReader(kind, addr string, baud int) *gpsReader {
+	gpsReadersMu.Lock()
+	defer gpsReadersMu.Unlock()
+
+	key := kind + "://" + addr
+	if r, ok := gpsReaders[key]; ok {
+		return r
+	}
+	r := &gpsReader{kind: kind, addr: addr, baud: baud}
+	gpsReaders[key] = r
+	go r.run()
+	return r
+}
+
+func (r *gpsReader) snapshot() (gpsFix, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.fix, r.connected
+}
+
+func (r *gpsReader) setConnected(c bool) {
+	r.mu.Lock()
+	r.connected = c
+	r.mu.Unlock()
+}
+
+// run reads forever, reconnecting with backoff (USB receivers get unplugged,
+// gpsd restarts).
+func (r *gpsReader) run() {
+	backoff := time.Second
+	for {
+		conn, err := r.open()
+		if err != nil {
+			r.setConnected(false)
+			time.Sleep(backoff)
+			backoff = min(backoff*2, 30*time.Second)
+			continue
+		}
+		r.setConnected(true)
+		backoff = time.Second
+
+		err = r.read(conn)
+		conn.Close()
+		r.setConnected(false)
+		log.Printf("⚠️ GPS %s %s lost: %v", r.kind, r.addr, err)
+		time.Sleep(time.Second)
+	}
+}
+
+func (r *gpsReader) open() (io.ReadWriteCloser, error) {
+	if r.kind == "serial" {
+		return serial.Open(r.addr, &serial.Mode{BaudRate: r.baud})
+	}
+
+	conn, err := net.DialTimeout("tcp", r.addr, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if r.kind == "gpsd" {
+		if _, err := io.WriteString(conn, `?WATCH={"enable":true,"json":true}`+"\n"); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (r *gpsReader) read(conn io.ReadWriteCloser) error {
+	scanner := bufio.NewScanner(conn)
+	for {
+		// Receivers talk at least once a second; silence means a dead peer
+		if c, ok := conn.(net.Conn); ok {
+			c.SetReadDeadline(time.Now().Add(30 * time.Second))
+		}
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return err
+			}
+			return io.EOF
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		r.mu.Lock()
+		if r.kind == "gpsd" {
+			r.fix.applyGPSD(line)
+		} else {
+			r.fix.applyNMEA(line)
+		}
+		r.mu.Unlock()
+	}
+}
+
+// applyNMEA updates the fix from one GGA, RMC or VTG sentence (any talker).
+// Sentences with a bad checksum are ignored.
+func (f *gpsFix) applyNMEA(line string) {
+	if !strings.HasPrefix(line, "$") {
+		return
+	}
+	body := line[1:]
+	if i := strings.LastIndexByte(body, '*'); i >= 0 {
+		want, err := strconv.ParseUint(body[i+1:], 16, 8)
+		if err != nil {
+			return
+		}
+		var sum byte
+		for j := 0; j < i; j++ {
+			sum ^= body[j]
+		}
+		if sum != byte(want) {
+			return
+		}
+		body = body[:i]
+	}
+
+	fields := strings.Split(body, ",")
+	if len(fields[0]) != 5 { // talker (GP, GN, GL...) + sentence type
+		return
+	}
+	field := func(i int) string {
+		if i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	num := func(i int) (float64, bool) {
+		v, err := strconv.ParseFloat(field(i), 64)
+		return v, err == nil
+	}
+
+	switch fields[0][2:] {
+	case "GGA":
+		f.sawGGA = true
+		q, _ := strconv.ParseInt(field(6), 10, 64)
+		f.quality = q
+		if sats, err := strconv.ParseInt(field(7), 10, 64); err == nil {
+			f.sats = sats
+		}
+		if hdop, ok := num(8); ok {
+			f.hdop = hdop
+		}
+		if q == 0 {
+			return
+		}
+		lat, ok1 := nmeaCoord(field(2), field(3))
+		lon, ok2 := nmeaCoord(field(4), field(5))
+		if !ok1 || !ok2 {
+			return
+		}
+		f.lat, f.lon = lat, lon
+		if alt, ok := num(9); ok {
+			f.alt, f.hasAlt = alt, true
+		}
+		f.updated = time.Now()
+
+	case "RMC":
+		valid := field(2) == "A"
+		if !f.sawGGA {
+			// Some receivers only send RMC; A/V is all the fix quality we get
+			f.quality = boolToInt(valid)
+		}
+		if !valid {
+			return
+		}
+		if knots, ok := num(7); ok {
+			f.speedKmh, f.hasMove = knots*1.852, true
+		}
+		if course, ok := num(8); ok {
+			f.course = course
+		}
+		if !f.sawGGA {
+			lat, ok1 := nmeaCoord(field(3), field(4))
+			lon, ok2 := nmeaCoord(field(5), field(6))
+			if ok1 && ok2 {
+				f.lat, f.lon = lat, lon
+				f.updated = time.Now()
+			}
+		}
+
+	case "VTG":
+		if kmh, ok := num(7); ok {
+			f.speedKmh, f.hasMove = kmh, true
+		}
+		if course, ok := num(1); ok {
+			f.course = course
+		}
+	}
+}
+
+// nmeaCoord turns "4807.038","N" (ddmm.mmmm) into 48.1173.
+func nmeaCoord(v, hemi string) (float64, bool) {
+	raw, err := strconv.ParseFloat(v, 64)
+	if err != nil || hemi == "" {
+		return 0, false
+	}
+	deg := float64(int(raw / 100))
+	out := deg + (raw-deg*100)/60
+	if hemi == "S" || hemi == "W" {
+		out = -out
+	}
+	return out, true
+}
+
+// applyGPSD updates the fix from a gpsd JSON report (TPV and SKY classes).
+func (f *gpsFix) applyGPSD(line string) {
+	var msg struct {
+		Class  string   `json:"class"`
+		Mode   int      `json:"mode"`   // 0/1 no fix, 2 2D, 3 3D
+		Status int      `json:"status"` // 2 DGPS, 3 RTK fixed, 4 RTK float
+		Lat    *float64 `json:"lat"`
+		Lon    *float64 `json:"lon"`
+		AltMSL *float64 `json:"altMSL"`
+		Alt    *float64 `json:"alt"` // pre-3.20 gpsd
+		Speed  *float64 `json:"speed"`
+		Track  *float64 `json:"track"`
+
+		HDOP       float64 `json:"hdop"`
+		USat       *int64  `json:"uSat"`
+		Satellites []struct {
+			Used bool `json:"used"`
+		} `json:"satellites"`
+	}
+	if json.Unmarshal([]byte(line), &msg) != nil {
+		return
+	}
+
+	switch msg.Class {
+	case "TPV":
+		if msg.Mode < 2 || msg.Lat == nil || msg.Lon == nil {
+			f.quality = 0
+			return
+		}
+		switch msg.Status {
+		case 2:
+			f.quality = 2
+		case 3:
+			f.quality = 4
+		case 4:
+			f.quality = 5
+		default:
+			f.quality = 1
+		}
+		f.lat, f.lon = *msg.Lat, *msg.Lon
+		f.hasAlt = false
+		if alt := msg.AltMSL; alt != nil || msg.Alt != nil {
+			if alt == nil {
+				alt = msg.Alt
+			}
+			f.alt, f.hasAlt = *alt, msg.Mode == 3
+		}
+		if msg.Speed != nil {
+			f.speedKmh, f.hasMove = *msg.Speed*3.6, true
+		}
+		if msg.Track != nil {
+			f.course = *msg.Track
+		}
+		f.updated = time.Now()
+
+	case "SKY":
+		if msg.HDOP > 0 {
+			f.hdop = msg.HDOP
+		}
+		if msg.USat != nil {
+			f.sats = *msg.USat
+		} else if len(msg.Satellites) > 0 {
+			var used int64
+			for _, s := range msg.Satellites {
+				if s.Used {
+					used++
+				}
+			}
+			f.sats = used
+		}
+	}
+}
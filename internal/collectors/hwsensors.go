@@ -0,0 +1,332 @@
+package collectors
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SensorsCollector reads temperatures, fan speeds, voltages, batteries and
+// throttling state from Linux sysfs. Key names are built from the chip and
+// sensor labels, e.g. temp_coretemp_package_id_0_c, temp_cpu_thermal_c,
+// fan_nct6775_2_rpm, bat0_charge_pct.
+//
+//	--param root=/sys   (point at a copy of the tree for testing)
+//
+// The system source includes the same keys with sensors=true.
+func SensorsCollector(params map[string]string) ([]map[string]interface{}, error) {
+	root := params["root"]
+	if root == "" {
+		root = "/sys"
+	}
+
+	m := readSensors(root)
+	if len(m) == 0 {
+		return nil, fmt.Errorf("no hardware sensors found under %s", root)
+	}
+	return []map[string]interface{}{m}, nil
+}
+
+// readSensors collects everything that exists; missing pieces are skipped.
+func readSensors(root string) map[string]interface{} {
+	m := make(map[string]interface{})
+	readHwmon(root, m)
+	readThermalZones(root, m)
+	readPowerSupplies(root, m)
+	readThrottling(root, m)
+
+	// Hottest reading, for a single alert threshold across chips
+	maxTemp, found := 0.0, false
+	for k, v := range m {
+		if f, ok := v.(float64); ok && strings.HasSuffix(k, "_c") && (strings.HasPrefix(k, "temp_") || strings.HasPrefix(k, "thermal_")) {
+			if !found || f > maxTemp {
+				maxTemp, found = f, true
+			}
+		}
+	}
+	if found {
+		m["temp_max_c"] = maxTemp
+	}
+	return m
+}
+
+// hwmon sensor files: <kind><n>_input, with an optional <kind><n>_label.
+var hwmonInput = regexp.MustCompile(`^(temp|fan|in|curr|power)(\d+)_(input|average)$`)
+
+func readHwmon(root string, m map[string]interface{}) {
+	dirs := sortedByIndex(filepath.Join(root, "class/hwmon/hwmon*"), "hwmon")
+
+	chips := make([]string, len(dirs))
+	seen := make(map[string]int)
+	for i, dir := range dirs {
+		chips[i] = sensorName(readSysString(filepath.Join(dir, "name")))
+		if chips[i] == "" {
+			chips[i] = filepath.Base(dir)
+		}
+		seen[chips[i]]++
+	}
+
+	alarms := int64(0)
+	for i, dir := range dirs {
+		chip := chips[i]
+		if seen[chip] > 1 { // e.g. two nvme drives
+			chip += "_" + strings.TrimPrefix(filepath.Base(dir), "hwmon")
+		}
+
+		// Older drivers keep the attributes under device/
+		if !hwmonHasAttrs(dir) && hwmonHasAttrs(filepath.Join(dir, "device")) {
+			dir = filepath.Join(dir, "device")
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		type reading struct {
+			kind, label string
+			value       float64
+		}
+		var readings []reading
+		perKind := make(map[string]int)
+		for _, e := range entries {
+			name := e.Name()
+			if strings.HasSuffix(name, "_alarm") {
+				if v, ok := readSysInt(filepath.Join(dir, name)); ok && v != 0 {
+					alarms++
+				}
+				continue
+			}
+			match := hwmonInput.FindStringSubmatch(name)
+			if match == nil || (match[3] == "average" && fileExists(filepath.Join(dir, match[1]+match[2]+"_input"))) {
+				continue
+			}
+			v, ok := readSysInt(filepath.Join(dir, name))
+			if !ok {
+				continue
+			}
+			label := sensorName(readSysString(filepath.Join(dir, match[1]+match[2]+"_label")))
+			if label == "" {
+				label = match[2]
+			}
+			readings = append(readings, reading{match[1], label, float64(v)})
+			perKind[match[1]]++
+		}
+
+		for _, r := range readings {
+			// A lone unlabeled sensor is just named after the chip (temp_cpu_thermal_c)
+			base := chip + "_" + r.label
+			if perKind[r.kind] == 1 && r.label == "1" {
+				base = chip
+			}
+			switch r.kind {
+			case "temp":
+				m["temp_"+base+"_c"] = r.value / 1000
+			case "fan":
+				m["fan_"+base+"_rpm"] = int64(r.value)
+			case "in":
+				m["voltage_"+base+"_v"] = r.value / 1000
+			case "curr":
+				m["current_"+base+"_a"] = r.value / 1000
+			case "power":
+				m["power_"+base+"_w"] = r.value / 1e6
+			}
+		}
+	}
+	if len(dirs) > 0 {
+		m["hwmon_alarms"] = alarms
+	}
+}
+
+// hwmonHasAttrs reports whether dir holds sensor readings or alarm flags.
+func hwmonHasAttrs(dir string) bool {
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if hwmonInput.MatchString(e.Name()) || strings.HasSuffix(e.Name(), "_alarm") {
+			return true
+		}
+	}
+	return false
+}
+
+func readThermalZones(root string, m map[string]interface{}) {
+	dirs := sortedByIndex(filepath.Join(root, "class/thermal/thermal_zone*"), "thermal_zone")
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		v, ok := readSysInt(filepath.Join(dir, "temp"))
+		if !ok {
+			continue
+		}
+		name := sensorName(readSysString(filepath.Join(dir, "type")))
+		if name == "" || seen[name] {
+			name += strings.TrimPrefix(filepath.Base(dir), "thermal_zone")
+		}
+		seen[name] = true
+		m["thermal_"+name+"_c"] = float64(v) / 1000
+	}
+}
+
+// readPowerSupplies reports batteries (<name>_charge_pct, _charging,
+// _health_ok, ...) and ac_online for mains/USB adapters.
+func readPowerSupplies(root string, m map[string]interface{}) {
+	dirs, _ := filepath.Glob(filepath.Join(root, "class/power_supply/*"))
+	sort.Strings(dirs)
+
+	acFound, acOnline := false, int64(0)
+	for _, dir := range dirs {
+		read := func(f string) (int64, bool) { return readSysInt(filepath.Join(dir, f)) }
+
+		switch readSysString(filepath.Join(dir, "type")) {
+		case "Mains", "USB", "USB_C", "USB_PD":
+			if v, ok := read("online"); ok {
+				acFound = true
+				if v != 0 {
+					acOnline = 1
+				}
+			}
+
+		case "Battery":
+			if scope := readSysString(filepath.Join(dir, "scope")); scope == "Device" {
+				continue // mice, keyboards
+			}
+			p := sensorName(filepath.Base(dir)) + "_"
+			if v, ok := read("capacity"); ok {
+				m[p+"charge_pct"] = v
+			}
+			if s := readSysString(filepath.Join(dir, "status")); s != "" {
+				m[p+"charging"] = boolToInt(s == "Charging")
+			}
+			if s := readSysString(filepath.Join(dir, "health")); s != "" && s != "Unknown" {
+				m[p+"health_ok"] = boolToInt(s == "Good")
+			}
+			if v, ok := read("cycle_count"); ok {
+				m[p+"cycles"] = v
+			}
+			if v, ok := read("temp"); ok {
+				m[p+"temp_c"] = float64(v) / 10
+			}
+
+			volts, hasVolts := read("voltage_now")
+			if hasVolts {
+				m[p+"voltage_v"] = float64(volts) / 1e6
+			}
+			if v, ok := read("power_now"); ok {
+				m[p+"power_w"] = float64(v) / 1e6
+			} else if a, ok := read("current_now"); ok && hasVolts {
+				m[p+"power_w"] = float64(a) * float64(volts) / 1e12
+			}
+
+			// Wear: full capacity now vs. as designed (energy in µWh or charge in µAh)
+			for _, unit := range []string{"energy", "charge"} {
+				full, ok1 := read(unit + "_full")
+				design, ok2 := read(unit + "_full_design")
+				if ok1 && ok2 && design > 0 {
+					m[p+"health_pct"] = float64(full) / float64(design) * 100
+					break
+				}
+			}
+		}
+	}
+	if acFound {
+		m["ac_online"] = acOnline
+	}
+}
+
+// Raspberry Pi firmware throttling bits (same as vcgencmd get_throttled)
+var rpiThrottleBits = []struct {
+	bit  uint
+	name string
+}{
+	{0, "undervoltage"},
+	{1, "freq_capped"},
+	{2, "active"},
+	{3, "soft_temp_limit"},
+}
+
+func readThrottling(root string, m map[string]interface{}) {
+	for _, f := range []string{
+		"devices/platform/soc/soc:firmware/get_throttled",
+		"devices/platform/soc/soc:firmware/raspberrypi-hwmon/get_throttled",
+	} {
+		s := readSysString(filepath.Join(root, f))
+		if s == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 32)
+		if err != nil {
+			continue
+		}
+		for _, b := range rpiThrottleBits {
+			m["throttle_"+b.name] = int64(v >> b.bit & 1)
+			m["throttle_"+b.name+"_since_boot"] = int64(v >> (b.bit + 16) & 1)
+		}
+		break
+	}
+
+	// x86: thermal throttle events since boot, and current clock speed
+	var coreEvents, pkgEvents, freqSum, freqN int64
+	cpus, _ := filepath.Glob(filepath.Join(root, "devices/system/cpu/cpu[0-9]*"))
+	for _, cpu := range cpus {
+		if v, ok := readSysInt(filepath.Join(cpu, "thermal_throttle/core_throttle_count")); ok {
+			coreEvents += v
+		}
+		if v, ok := readSysInt(filepath.Join(cpu, "thermal_throttle/package_throttle_count")); ok {
+			pkgEvents = max(pkgEvents, v) // every core repeats its package's count
+		}
+		if v, ok := readSysInt(filepath.Join(cpu, "cpufreq/scaling_cur_freq")); ok {
+			freqSum += v
+			freqN++
+		}
+	}
+	if fileExists(filepath.Join(root, "devices/system/cpu/cpu0/thermal_throttle")) {
+		m["throttle_core_events"] = coreEvents
+		m["throttle_package_events"] = pkgEvents
+	}
+	if freqN > 0 {
+		m["cpu_freq_mhz"] = float64(freqSum) / float64(freqN) / 1000
+	}
+}
+
+// sortedByIndex globs pattern and orders matches by the number after prefix
+// (hwmon2 before hwmon10).
+func sortedByIndex(pattern, prefix string) []string {
+	dirs, _ := filepath.Glob(pattern)
+	index := func(d string) int {
+		n, _ := strconv.Atoi(strings.TrimPrefix(filepath.Base(d), prefix))
+		return n
+	}
+	sort.Slice(dirs, func(i, j int) bool { return index(dirs[i]) < index(dirs[j]) })
+	return dirs
+}
+
+var sensorNameReplacer = regexp.MustCompile(`[^a-z0-9]+`)
+
+// sensorName turns "Package id 0" into "package_id_0".
+func sensorName(s string) string {
+	return strings.Trim(sensorNameReplacer.ReplaceAllString(strings.ToLower(s), "_"), "_")
+}
+
+func readSysString(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func readSysInt(path string) (int64, bool) {
+	s := readSysString(path)
+	if s == "" {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	return v, err == nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
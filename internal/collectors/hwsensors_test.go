@@ -0,0 +1,159 @@
+package collectors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeSysfs creates files (path relative to root -> contents) in a fake sysfs tree.
+func writeSysfs(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadSensors(t *testing.T) {
+	root := t.TempDir()
+	writeSysfs(t, root, map[string]string{
+		// Labeled sensors
+		"class/hwmon/hwmon0/name":        "coretemp",
+		"class/hwmon/hwmon0/temp1_input": "45000",
+		"class/hwmon/hwmon0/temp1_label": "Package id 0",
+		"class/hwmon/hwmon0/temp2_input": "41000",
+		"class/hwmon/hwmon0/temp2_label": "Core 0",
+
+		// Two chips with the same name get their hwmon index
+		"class/hwmon/hwmon1/name":         "nvme",
+		"class/hwmon/hwmon1/temp1_input":  "38850",
+		"class/hwmon/hwmon1/temp1_label":  "Composite",
+		"class/hwmon/hwmon10/name":        "nvme",
+		"class/hwmon/hwmon10/temp1_input": "36850",
+
+		// Older driver with its attributes under device/
+		"class/hwmon/hwmon2/name":               "it8728",
+		"class/hwmon/hwmon2/device/fan1_input":  "1250",
+		"class/hwmon/hwmon2/device/fan2_input":  "0",
+		"class/hwmon/hwmon2/device/in0_input":   "1212",
+		"class/hwmon/hwmon2/device/in0_alarm":   "1",
+		"class/hwmon/hwmon2/device/curr1_input": "2500",
+
+		// Alarm-only chip (Pi under-voltage): must not switch to device/
+		"class/hwmon/hwmon3/name":                   "rpi_volt",
+		"class/hwmon/hwmon3/in0_lcrit_alarm":        "1",
+		"class/hwmon/hwmon3/device/unrelated_input": "5",
+
+		// power1_average counts when there is no power1_input
+		"class/hwmon/hwmon4/name":           "amdgpu",
+		"class/hwmon/hwmon4/power1_average": "35000000",
+
+		// Thermal zones, one duplicate type and one without a type
+		"class/thermal/thermal_zone0/type": "cpu-thermal",
+		"class/thermal/thermal_zone0/temp": "52500",
+		"class/thermal/thermal_zone1/type": "cpu-thermal",
+		"class/thermal/thermal_zone1/temp": "51000",
+		"class/thermal/thermal_zone2/temp": "30000",
+		"class/thermal/thermal_zone3/type": "broken",
+
+		// Batteries and an adapter
+		"class/power_supply/AC/type":                 "Mains",
+		"class/power_supply/AC/online":               "1",
+		"class/power_supply/BAT0/type":               "Battery",
+		"class/power_supply/BAT0/capacity":           "87",
+		"class/power_supply/BAT0/status":             "Charging",
+		"class/power_supply/BAT0/health":             "Good",
+		"class/power_supply/BAT0/cycle_count":        "120",
+		"class/power_supply/BAT0/temp":               "296",
+		"class/power_supply/BAT0/voltage_now":        "12000000",
+		"class/power_supply/BAT0/current_now":        "1500000",
+		"class/power_supply/BAT0/energy_full":        "45000000",
+		"class/power_supply/BAT0/energy_full_design": "50000000",
+		"class/power_supply/BAT1/type":               "Battery",
+		"class/power_supply/BAT1/status":             "Discharging",
+		"class/power_supply/BAT1/health":             "Overheat",
+		"class/power_supply/BAT1/power_now":          "7500000",
+		"class/power_supply/BAT1/charge_full":        "3000000",
+		"class/power_supply/BAT1/charge_full_design": "4000000",
+		"class/power_supply/hid-mouse/type":          "Battery",
+		"class/power_supply/hid-mouse/scope":         "Device",
+		"class/power_supply/hid-mouse/capacity":      "40",
+
+		// Raspberry Pi: under-voltage and throttled now, freq capped since boot
+		"devices/platform/soc/soc:firmware/get_throttled": "0x20005",
+
+		// x86 throttle counters and clocks
+		"devices/system/cpu/cpu0/thermal_throttle/core_throttle_count":    "3",
+		"devices/system/cpu/cpu0/thermal_throttle/package_throttle_count": "7",
+		"devices/system/cpu/cpu0/cpufreq/scaling_cur_freq":                "2000000",
+		"devices/system/cpu/cpu1/thermal_throttle/core_throttle_count":    "2",
+		"devices/system/cpu/cpu1/thermal_throttle/package_throttle_count": "7",
+		"devices/system/cpu/cpu1/cpufreq/scaling_cur_freq":                "3000000",
+	})
+
+	m := readSensors(root)
+	assertMetrics(t, m, map[string]interface{}{
+		"temp_coretemp_package_id_0_c": 45.0,
+		"temp_coretemp_core_0_c":       41.0,
+		"temp_nvme_1_composite_c":      38.85,
+		"temp_nvme_10_c":               36.85, // lone unlabeled sensor: just the chip
+		"fan_it8728_1_rpm":             int64(1250),
+		"fan_it8728_2_rpm":             int64(0),
+		"voltage_it8728_0_v":           1.212,
+		"current_it8728_a":             2.5,
+		"power_amdgpu_w":               35.0,
+		"hwmon_alarms":                 int64(2),
+
+		"thermal_cpu_thermal_c":  52.5,
+		"thermal_cpu_thermal1_c": 51.0,
+		"thermal_2_c":            30.0,
+		"temp_max_c":             52.5,
+
+		"ac_online":       int64(1),
+		"bat0_charge_pct": int64(87),
+		"bat0_charging":   int64(1),
+		"bat0_health_ok":  int64(1),
+		"bat0_cycles":     int64(120),
+		"bat0_temp_c":     29.6,
+		"bat0_voltage_v":  12.0,
+		"bat0_power_w":    18.0, // current_now * voltage_now
+		"bat0_health_pct": 90.0,
+		"bat1_charging":   int64(0),
+		"bat1_health_ok":  int64(0),
+		"bat1_power_w":    7.5,
+		"bat1_health_pct": 75.0,
+
+		"throttle_undervoltage":            int64(1),
+		"throttle_freq_capped":             int64(0),
+		"throttle_active":                  int64(1),
+		"throttle_soft_temp_limit":         int64(0),
+		"throttle_undervoltage_since_boot": int64(0),
+		"throttle_freq_capped_since_boot":  int64(1),
+		"throttle_core_events":             int64(5),
+		"throttle_package_events":          int64(7),
+		"cpu_freq_mhz":                     2500.0,
+	})
+
+	for _, k := range []string{"hid_mouse_charge_pct", "bat1_voltage_v", "temp_rpi_volt_c", "thermal_broken_c"} {
+		if _, ok := m[k]; ok {
+			t.Errorf("%s should not be set", k)
+		}
+	}
+	for k, v := range m {
+		if _, ok := v.(string); ok {
+			t.Errorf("%s = %q: only numbers are sent", k, v)
+		}
+	}
+}
+
+func TestSensorsCollectorEmptyTree(t *testing.T) {
+	if _, err := SensorsCollector(map[string]string{"root": t.TempDir()}); err == nil {
+		t.Error("expected an error for a tree without sensors")
+	}
+}
@@ -0,0 +1,198 @@
+package collectors
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	listenDefaultAddr = ":8088"
+	listenMaxBody     = 1 << 20 // 1 MB per request
+	listenMaxBuffer   = 10000   // rows held between flushes
+)
+
+// pushBuffer holds rows POSTed to one path until the next collector tick.
+type pushBuffer struct {
+	mu      sync.Mutex
+	rows    []map[string]interface{}
+	token   string
+	max     int
+	dropped int64
+	reqs    int64
+}
+
+// pushServer is one HTTP listener; several instances may share it on different paths.
+type pushServer struct {
+	mux     *http.ServeMux
+	buffers map[string]*pushBuffer
+}
+
+var (
+	pushServersMu sync.Mutex
+	pushServers   = make(map[string]*pushServer) // keyed by listen address
+)
+
+// ListenCollector exposes a local HTTP endpoint that accepts the same JSON
+// as exec output (an object or an array of objects, with optional ship_id)
+// and hands everything received since the last tick to the worker.
+func ListenCollector(params map[string]string) ([]map[string]interface{}, error) {
+	addr := params["listen"]
+	if addr == "" {
+		addr = listenDefaultAddr
+	}
+	path := params["path"]
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // "webhook" -> "/webhook"; ServeMux panics without the slash
+	}
+
+	buf, err := getPushBuffer(addr, path, params)
+	if err != nil {
+		return nil, err
+	}
+
+	buf.mu.Lock()
+	defer buf.mu.Unlock()
+
+	rows := buf.rows
+	buf.rows = nil
+
+	if paramBool(params, "stats") {
+		rows = append(rows, map[string]interface{}{
+			"listen_requests":     buf.reqs,
+			"listen_rows":         int64(len(rows)),
+			"listen_rows_dropped": buf.dropped,
+		})
+	}
+	buf.reqs, buf.dropped = 0, 0
+
+	if rows == nil {
+		return []map[string]interface{}{}, nil
+	}
+	return rows, nil
+}
+
+func getPushBuffer(addr, path string, params map[string]string) (*pushBuffer, error) {
+	pushServersMu.Lock()
+	defer pushServersMu.Unlock()
+
+	srv, ok := pushServers[addr]
+	if !ok {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("listen %s: %w", addr, err)
+		}
+		srv = &pushServer{mux: http.NewServeMux(), buffers: make(map[string]*pushBuffer)}
+		pushServers[addr] = srv
+
+		httpSrv := &http.Server{
+			Handler:           srv.mux,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+		}
+		log.Printf("📥 Push receiver listening on http://%s", ln.Addr())
+		go func() {
+			if err := httpSrv.Serve(ln); err != nil {
+				log.Printf("⚠️ Push receiver on %s stopped: %v", addr, err)
+			}
+		}()
+	}
+
+	if buf, ok := srv.buffers[path]; ok {
+		// The first instance on a path set its token; don't let another
+		// instance believe its own token protects the same endpoint
+		if buf.token != params["token"] {
+			return nil, fmt.Errorf("path %q on %s is already served with a different token", path, addr)
+		}
+		return buf, nil
+	}
+
+	buf := &pushBuffer{
+		token: params["token"],
+		max:   paramInt(params, "max_buffer", listenMaxBuffer),
+	}
+	if err := handleSafe(srv.mux, path, buf); err != nil {
+		return nil, fmt.Errorf("path %q on %s: %w", path, addr, err)
+	}
+	srv.buffers[path] = buf
+	return buf, nil
+}
+
+// handleSafe registers h on mux, turning ServeMux's panics (invalid or
+// conflicting pattern) into an error so one bad instance can't take the
+// daemon down.
+func handleSafe(mux *http.ServeMux, pattern string, h http.Handler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	mux.Handle(pattern, h)
+	return nil
+}
+
+func (b *pushBuffer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if b.token != "" {
+		got, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(b.token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, listenMaxBody))
+	if err != nil {
+		http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Same shapes the exec collector accepts: one object, or an array of them
+	var rows []map[string]interface{}
+	var row map[string]interface{}
+	if err := json.Unmarshal(body, &row); err == nil {
+		rows = []map[string]interface{}{row}
+	} else if err := json.Unmarshal(body, &rows); err != nil {
+		http.Error(w, "expected a JSON object or array of objects", http.StatusBadRequest)
+		return
+	}
+	// null, [null] and {} decode without error but carry nothing to send
+	for _, row := range rows {
+		if len(row) == 0 {
+			http.Error(w, "empty or null object", http.StatusBadRequest)
+			return
+		}
+	}
+
+	b.mu.Lock()
+	b.reqs++
+	room := max(b.max-len(b.rows), 0)
+	full := len(rows) > 0 && room == 0
+	if len(rows) > room {
+		b.dropped += int64(len(rows) - room)
+		rows = rows[:room]
+	}
+	b.rows = append(b.rows, rows...)
+	b.mu.Unlock()
+
+	if full {
+		http.Error(w, "buffer full, retry later", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(w, `{"accepted":%d}`, len(rows))
+}
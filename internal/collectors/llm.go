@@ -0,0 +1,240 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// LLMCollector backs the generic llm/ai sources. api=ollama (the default,
+// so existing services keep working) uses the Ollama API; api=openai talks
+// to any OpenAI-compatible server (vLLM, llama.cpp, LM Studio, LocalAI...).
+func LLMCollector(params map[string]string) ([]map[string]interface{}, error) {
+	switch strings.ToLower(params["api"]) {
+	case "", "ollama":
+		return OllamaCollector(params)
+	case "openai", "vllm", "llamacpp", "llama.cpp":
+		return OpenAICollector(params)
+	default:
+		return nil, fmt.Errorf("unknown llm api %q (use ollama or openai)", params["api"])
+	}
+}
+
+// OpenAICollector checks an OpenAI-compatible inference server: the model
+// list, its health endpoint, an optional synthetic chat completion and the
+// server's own Prometheus metrics (vLLM always, llama.cpp with --metrics).
+//
+//	--param url=http://gpu-box:8000       (Default: http://localhost:8000)
+//	--param api_key=sk-...
+//	--param probe_model=auto              (auto = first listed model)
+//	--param metrics=false                 (skip the /metrics scrape)
+func OpenAICollector(params map[string]string) ([]map[string]interface{}, error) {
+	base := strings.TrimSuffix(params["url"], "/")
+	if base == "" {
+		base = "http://localhost:8000"
+	}
+	// Accept both the server root and the .../v1 API base
+	base = strings.TrimSuffix(base, "/v1")
+	apiKey := params["api_key"]
+
+	client := &http.Client{Timeout: paramMillis(params, "timeout_ms", 5*time.Second)}
+
+	// "" is the instance's own ship; metrics may add more via ship_label
+	ships := map[string]map[string]interface{}{"": {"llm_up": int64(0)}}
+	summary := ships[""]
+
+	// 1. Is it up? (GET /v1/models)
+	var models []string
+	start := time.Now()
+	resp, err := llmRequest(client, "GET", base+"/v1/models", apiKey, nil)
+	if err != nil {
+		return []map[string]interface{}{summary}, nil
+	}
+	summary["llm_latency_ms"] = time.Since(start).Milliseconds()
+	summary["llm_status_code"] = int64(resp.StatusCode)
+	if resp.StatusCode == 200 {
+		var list struct {
+			Data []struct {
+				ID string `json:"id"`
+			} `json:"data"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&list) == nil {
+			summary["llm_up"] = int64(1)
+			for _, m := range list.Data {
+				models = append(models, m.ID)
+			}
+			summary["llm_models"] = int64(len(models))
+		}
+	}
+	resp.Body.Close()
+
+	// 2. Health endpoint (vLLM / llama.cpp: 200 ok, 503 while loading). -1 = not offered
+	summary["llm_healthy"] = int64(-1)
+	if resp, err := llmRequest(client, "GET", base+"/health", apiKey, nil); err == nil {
+		if resp.StatusCode != 404 {
+			summary["llm_healthy"] = boolToInt(resp.StatusCode == 200)
+		}
+		resp.Body.Close()
+	}
+
+	// 3. Native server metrics
+	if params["metrics"] == "" || paramBool(params, "metrics") {
+		sc, err := scrapeProm(client, base+"/metrics", apiKey)
+		summary["llm_metrics_up"] = boolToInt(err == nil)
+		if err == nil {
+			host := base
+			if u, err := url.Parse(base); err == nil && u.Host != "" {
+				host = u.Host
+			}
+			newPromOptions(params).convert(sc, host, ships)
+		}
+	}
+
+	// 4. Optional synthetic completion
+	if model := params["probe_model"]; model != "" {
+		if model == "auto" && len(models) > 0 {
+			model = models[0]
+		}
+		if model != "auto" {
+			for k, v := range openAIProbe(base, apiKey, model, params) {
+				summary[k] = v
+			}
+		}
+	}
+
+	results := []map[string]interface{}{summary}
+	for id, m := range ships {
+		if id == "" || len(m) == 0 {
+			continue
+		}
+		m["ship_id"] = params["ship_prefix"] + id
+		results = append(results, m)
+	}
+	return results, nil
+}
+
+func llmRequest(client *http.Client, method, target, apiKey string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	return client.Do(req)
+}
+
+// openAIProbe streams a short chat completion and times it. Token counts come
+// from the final usage chunk when the server sends one, else from the number
+// of content deltas (one token each on vLLM and llama.cpp).
+func openAIProbe(base, apiKey, model string, params map[string]string) map[string]interface{} {
+	result := map[string]interface{}{
+		"llm_probe_up":         int64(0),
+		"llm_probe_latency_ms": int64(0),
+	}
+
+	prompt := params["probe_prompt"]
+	if prompt == "" {
+		prompt = "Reply with the single word OK."
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":          model,
+		"messages":       []map[string]string{{"role": "user", "content": prompt}},
+		"max_tokens":     paramInt(params, "probe_max_tokens", 16),
+		"temperature":    0,
+		"stream":         true,
+		"stream_options": map[string]bool{"include_usage": true},
+	})
+
+	client := &http.Client{Timeout: paramMillis(params, "probe_timeout_ms", 60*time.Second)}
+	start := time.Now()
+	resp, err := llmRequest(client, "POST", base+"/v1/chat/completions", apiKey, body)
+	if err != nil {
+		return result
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		result["llm_probe_status_code"] = int64(resp.StatusCode)
+		return result
+	}
+
+	var firstToken, lastToken time.Time
+	var deltas, completionTokens, promptTokens int64
+	done := false
+
+	// Server-sent events: "data: {...}" lines, terminated by "data: [DONE]"
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content          string `json:"content"`
+					ReasoningContent string `json:"reasoning_content"`
+				} `json:"delta"`
+				FinishReason *string `json:"finish_reason"`
+			} `json:"choices"`
+			Usage *struct {
+				PromptTokens     int64 `json:"prompt_tokens"`
+				CompletionTokens int64 `json:"completion_tokens"`
+			} `json:"usage"`
+		}
+		if json.Unmarshal([]byte(data), &chunk) != nil {
+			continue
+		}
+		for _, c := range chunk.Choices {
+			if c.Delta.Content != "" || c.Delta.ReasoningContent != "" {
+				now := time.Now()
+				if firstToken.IsZero() {
+					firstToken = now
+				}
+				lastToken = now
+				deltas++
+			}
+			if c.FinishReason != nil && *c.FinishReason != "" {
+				done = true
+			}
+		}
+		if chunk.Usage != nil {
+			completionTokens = chunk.Usage.CompletionTokens
+			promptTokens = chunk.Usage.PromptTokens
+		}
+	}
+	if !done || firstToken.IsZero() {
+		return result
+	}
+
+	if completionTokens == 0 {
+		completionTokens = deltas
+	}
+	result["llm_probe_up"] = int64(1)
+	result["llm_probe_latency_ms"] = time.Since(start).Milliseconds()
+	result["llm_probe_ttft_ms"] = firstToken.Sub(start).Milliseconds()
+	result["llm_probe_tokens"] = completionTokens
+	if promptTokens > 0 {
+		result["llm_probe_prompt_tokens"] = promptTokens
+	}
+	// Generation speed after the first token (prefill is covered by TTFT)
+	if gen := lastToken.Sub(firstToken).Seconds(); gen > 0 && completionTokens > 1 {
+		result["llm_probe_tokens_per_sec"] = float64(completionTokens-1) / gen
+	}
+	return result
+}
@@ -0,0 +1,402 @@
+package collectors
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harborscale/harbor-lighthouse/internal/config"
+)
+
+const (
+	logtailStateFile = "logtail_offsets.json"
+	// Bytes hashed from the start of a file to recognise it after a restart
+	logtailFingerprintLen = 256
+	// Lines longer than this are skipped rather than buffered
+	logtailMaxLine = 64 * 1024
+	// Saved offsets not touched for this long (removed or renamed instances)
+	// are dropped
+	logtailStateTTL = 7 * 24 * time.Hour
+)
+
+// tailedFile is the persisted read position of one file for one instance.
+type tailedFile struct {
+	Offset      int64  `json:"offset"`
+	Fingerprint uint32 `json:"fingerprint"`
+	Long        bool   `json:"long,omitempty"` // Offset is inside an oversized line
+	Seen        int64  `json:"seen"`           // unix time of the last tick
+
+	f *os.File // open handle, kept across ticks to follow renames
+}
+
+var (
+	logtailMu      sync.Mutex
+	logtailState   map[string]*tailedFile  // key: logtailKey + "|" + path
+	logtailStarted = make(map[string]bool) // instances that have run in this process
+)
+
+// logPattern is one --param match.<name>=<regex>.
+type logPattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// valueStats aggregates numbers pulled from a named capture group.
+type valueStats struct {
+	count         int64
+	sum, min, max float64
+}
+
+func (s *valueStats) add(v float64) {
+	if s.count == 0 || v < s.min {
+		s.min = v
+	}
+	if s.count == 0 || v > s.max {
+		s.max = v
+	}
+	s.count++
+	s.sum += v
+}
+
+// LogTailCollector follows log files and turns matching lines into metrics.
+//
+//	--param path=/var/log/nginx/access.log            (comma separated, globs allowed)
+//	--param match.errors=" 5\d\d "                    -> logtail_errors_count
+//	--param match.req=" (?P<rt>[\d.]+)$"              -> logtail_req_count, logtail_req_rt_avg/min/max/sum
+func LogTailCollector(params map[string]string) ([]map[string]interface{}, error) {
+	var paths []string
+	for _, p := range splitList(params["path"]) {
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, fmt.Errorf("bad path pattern %q: %w", p, err)
+		}
+		paths = append(paths, matches...)
+	}
+	if len(paths) == 0 && params["path"] == "" {
+		return nil, fmt.Errorf("missing 'path' param")
+	}
+
+	patterns, err := logPatterns(params)
+	if err != nil {
+		return nil, err
+	}
+
+	key := logtailKey(params)
+
+	counts := make(map[string]int64)
+	values := make(map[string]*valueStats)
+	var lines, bytesRead int64
+
+	logtailMu.Lock()
+	defer logtailMu.Unlock()
+	if logtailState == nil {
+		logtailState = loadLogtailState()
+	}
+
+	// Only files that already exist on an instance's first run start at the
+	// end; files that show up later (app-2026-10-20.log) are read from the start
+	skipExisting := !paramBool(params, "from_beginning") && !logtailStarted[key] && !logtailHasState(key)
+	logtailStarted[key] = true
+
+	current := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		current[key+"|"+path] = true
+	}
+	collect := func(line string) {
+		lines++
+		for _, p := range patterns {
+			m := p.re.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			counts[p.name]++
+			for i, group := range p.re.SubexpNames() {
+				if group == "" || i >= len(m) {
+					continue
+				}
+				v, err := strconv.ParseFloat(m[i], 64)
+				if err != nil {
+					continue
+				}
+				id := p.name + "_" + group
+				if values[id] == nil {
+					values[id] = &valueStats{}
+				}
+				values[id].add(v)
+			}
+		}
+	}
+
+	// Files that left the glob (dated logs, deleted files): read what's left,
+	// then release the handle so deleted files don't stay pinned on disk
+	for k, st := range logtailState {
+		if strings.HasPrefix(k, key+"|") && !current[k] {
+			bytesRead += forgetTailed(k, st, collect)
+		}
+	}
+
+	for _, path := range paths {
+		n, err := tailFile(key+"|"+path, path, skipExisting, collect)
+		bytesRead += n
+		if err != nil {
+			saveLogtailState()
+			return nil, err
+		}
+	}
+
+	saveLogtailState()
+
+	result := map[string]interface{}{
+		"logtail_files":      int64(len(paths)),
+		"logtail_lines":      lines,
+		"logtail_bytes_read": bytesRead,
+	}
+	for _, p := range patterns {
+		result["logtail_"+p.name+"_count"] = counts[p.name]
+	}
+	for id, s := range values {
+		result["logtail_"+id+"_avg"] = s.sum / float64(s.count)
+		result["logtail_"+id+"_min"] = s.min
+		result["logtail_"+id+"_max"] = s.max
+		result["logtail_"+id+"_sum"] = s.sum
+	}
+
+	return []map[string]interface{}{result}, nil
+}
+
+func logPatterns(params map[string]string) ([]logPattern, error) {
+	var out []logPattern
+	for k, v := range params {
+		name, ok := strings.CutPrefix(k, "match.")
+		if !ok || name == "" {
+			continue
+		}
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, fmt.Errorf("bad regex for %s: %w", k, err)
+		}
+		out = append(out, logPattern{name: name, re: re})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out, nil
+}
+
+// tailFile reads complete lines appended since the last call. It handles
+// rotation (path now points at a new file) and truncation (file shrank).
+func tailFile(key, path string, skipExisting bool, onLine func(string)) (int64, error) {
+	st, ok := logtailState[key]
+	if !ok {
+		st = &tailedFile{Offset: -1}
+		logtailState[key] = st
+	}
+
+	st.Seen = time.Now().Unix()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		// Gone (deleted, or mid-rotation): finish the old handle and forget it.
+		// A new file at this path is picked up from its start next tick.
+		return forgetTailed(key, st, onLine), nil
+	}
+
+	var total int64
+
+	// Rotated: drain whatever was written to the old file, then switch
+	if st.f != nil {
+		if oldFi, err := st.f.Stat(); err != nil || !os.SameFile(oldFi, fi) {
+			n, _ := readLines(st.f, st, onLine)
+			total += n
+			st.f.Close()
+			st.f = nil
+			st.Offset = 0
+			st.Fingerprint = 0
+			st.Long = false
+		}
+	}
+
+	if st.f == nil {
+		f, err := os.Open(path)
+		if err != nil {
+			return total, fmt.Errorf("open %s: %w", path, err)
+		}
+		st.f = f
+
+		fp := fingerprint(f)
+		switch {
+		case st.Offset < 0 && skipExisting:
+			// Existed before the instance started: don't replay its history
+			st.Offset = fi.Size()
+		case st.Offset < 0, st.Fingerprint != 0 && fp != 0 && st.Fingerprint != fp:
+			// New file, or a different file than the one we saved an offset for
+			st.Offset = 0
+			st.Long = false
+		}
+		st.Fingerprint = fp
+	}
+
+	// Truncated in place (copytruncate)
+	if fi.Size() < st.Offset {
+		st.Offset = 0
+		st.Long = false
+	}
+
+	if st.Fingerprint == 0 {
+		st.Fingerprint = fingerprint(st.f)
+	}
+
+	n, err := readLines(st.f, st, onLine)
+	return total + n, err
+}
+
+// readLines consumes complete lines from st.Offset; a trailing partial line
+// is left for the next call. An oversized line is consumed as it arrives and
+// st.Long remembers to drop its remainder, even on a later tick.
+func readLines(f *os.File, st *tailedFile, onLine func(string)) (int64, error) {
+	if _, err := f.Seek(st.Offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	r := bufio.NewReaderSize(f, logtailMaxLine)
+	var total int64
+
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			total += int64(len(chunk))
+			st.Offset += int64(len(chunk))
+			st.Long = true
+			continue
+		}
+		if err != nil {
+			// EOF with a partial line: don't consume it yet. The tail of an
+			// oversized line is consumed, nothing of it is sent anyway.
+			if st.Long && len(chunk) > 0 {
+				total += int64(len(chunk))
+				st.Offset += int64(len(chunk))
+			}
+			return total, nil
+		}
+
+		total += int64(len(chunk))
+		st.Offset += int64(len(chunk))
+		if st.Long {
+			st.Long = false
+			continue
+		}
+		onLine(strings.TrimRight(string(chunk), "\r\n"))
+	}
+}
+
+// forgetTailed drains and closes an open handle and drops its state.
+func forgetTailed(key string, st *tailedFile, onLine func(string)) int64 {
+	var n int64
+	if st.f != nil {
+		n, _ = readLines(st.f, st, onLine)
+		st.f.Close()
+	}
+	delete(logtailState, key)
+	return n
+}
+
+// logtailHasState reports whether offsets were saved for the instance
+// (it ran before a daemon restart).
+func logtailHasState(instance string) bool {
+	for k := range logtailState {
+		if strings.HasPrefix(k, instance+"|") {
+			return true
+		}
+	}
+	return false
+}
+
+func fingerprint(f *os.File) uint32 {
+	buf := make([]byte, logtailFingerprintLen)
+	n, _ := f.ReadAt(buf, 0)
+	if n < logtailFingerprintLen {
+		// Too short to identify reliably; decide once it has grown
+		return 0
+	}
+	return crc32.ChecksumIEEE(buf[:n])
+}
+
+// logtailKey identifies an instance's offsets. Only the instance and its
+// path list count, so editing match.* keeps the read positions.
+func logtailKey(params map[string]string) string {
+	if name := params[InstanceParam]; name != "" {
+		return name
+	}
+	return paramsHash(map[string]string{"path": params["path"]})
+}
+
+// paramsHash keys state per instance configuration, so two instances
+// tailing the same file each see every line.
+func paramsHash(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := fnv.New64a()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, params[k])
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// logtailStatePath is empty when the config dir isn't set up (one-off runs,
+// tests): offsets are then only kept in memory.
+func logtailStatePath() string {
+	if config.GlobalDir == "" {
+		return ""
+	}
+	return filepath.Join(config.GlobalDir, logtailStateFile)
+}
+
+func loadLogtailState() map[string]*tailedFile {
+	state := make(map[string]*tailedFile)
+	path := logtailStatePath()
+	if path == "" {
+		return state
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state
+	}
+	_ = json.Unmarshal(data, &state)
+	return state
+}
+
+// saveLogtailState drops stale entries and writes the rest to disk.
+func saveLogtailState() {
+	cutoff := time.Now().Add(-logtailStateTTL).Unix()
+	for k, st := range logtailState {
+		if st.Seen < cutoff {
+			if st.f != nil {
+				st.f.Close()
+			}
+			delete(logtailState, k)
+		}
+	}
+
+	path := logtailStatePath()
+	if path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(logtailState, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(path, data, 0644)
+}
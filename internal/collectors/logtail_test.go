@@ -0,0 +1,130 @@
+package collectors
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harborscale/harbor-lighthouse/internal/config"
+)
+
+// resetLogtail gives a test fresh in-memory offsets, saved under dir when set.
+func resetLogtail(t *testing.T, dir string) {
+	t.Helper()
+	oldDir := config.GlobalDir
+	config.GlobalDir = dir
+	logtailState = nil
+	logtailStarted = make(map[string]bool)
+	t.Cleanup(func() {
+		for _, st := range logtailState {
+			if st.f != nil {
+				st.f.Close()
+			}
+		}
+		config.GlobalDir = oldDir
+		logtailState = nil
+		logtailStarted = make(map[string]bool)
+	})
+}
+
+func appendFile(t *testing.T, path, s string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func tailOnce(t *testing.T, params map[string]string) map[string]interface{} {
+	t.Helper()
+	res, err := LogTailCollector(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res[0]
+}
+
+func TestLogTailLongLineAcrossTicks(t *testing.T) {
+	resetLogtail(t, "")
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "first\n")
+	params := map[string]string{"path": path, "from_beginning": "true", "match.ok": "^ok$"}
+
+	assertMetrics(t, tailOnce(t, params), map[string]interface{}{"logtail_lines": int64(1)})
+
+	// An oversized line still being written when the tick runs...
+	appendFile(t, path, strings.Repeat("x", logtailMaxLine+100))
+	assertMetrics(t, tailOnce(t, params), map[string]interface{}{"logtail_lines": int64(0)})
+
+	// ...has its end dropped on the next one, not counted as a line
+	appendFile(t, path, "tail of the long line\nok\n")
+	assertMetrics(t, tailOnce(t, params), map[string]interface{}{
+		"logtail_lines":    int64(1),
+		"logtail_ok_count": int64(1),
+	})
+}
+
+func TestLogTailMatchEditKeepsOffsets(t *testing.T) {
+	resetLogtail(t, "")
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "history\n")
+	params := map[string]string{"path": path, InstanceParam: "web"}
+
+	assertMetrics(t, tailOnce(t, params), map[string]interface{}{"logtail_lines": int64(0)})
+
+	appendFile(t, path, "ERROR disk full\n")
+	params["match.errors"] = "ERROR"
+	assertMetrics(t, tailOnce(t, params), map[string]interface{}{
+		"logtail_lines":        int64(1),
+		"logtail_errors_count": int64(1),
+	})
+}
+
+func TestLogTailStatePersisted(t *testing.T) {
+	dir := t.TempDir()
+	resetLogtail(t, dir)
+	logDir := t.TempDir()
+	good := filepath.Join(logDir, "good.log")
+	bad := filepath.Join(logDir, "bad.log")
+	appendFile(t, good, "one\ntwo\n")
+	// A socket can be stat'ed but not opened
+	ln, err := net.Listen("unix", bad)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	params := map[string]string{"path": good + "," + bad, "from_beginning": "true", InstanceParam: "web"}
+
+	// An instance removed long ago
+	logtailState = map[string]*tailedFile{
+		"gone|/var/log/old.log": {Offset: 10, Seen: time.Now().Add(-logtailStateTTL - time.Hour).Unix()},
+	}
+
+	// bad.log can't be opened: the error is reported, good.log's progress kept
+	if _, err := LogTailCollector(params); err == nil {
+		t.Fatal("expected an error for an unreadable file")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, logtailStateFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]*tailedFile
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if st := saved["web|"+good]; st == nil || st.Offset != 8 {
+		t.Errorf("good.log state = %+v, want offset 8", st)
+	}
+	if _, ok := saved["gone|/var/log/old.log"]; ok {
+		t.Error("stale state not pruned")
+	}
+}
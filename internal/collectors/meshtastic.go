@@ -0,0 +1,450 @@
+package collectors
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Meshtastic stream API: every protobuf is framed as 0x94 0xC3 <len hi> <len lo>.
+// Anything outside a frame on serial is the device's debug log.
+const (
+	meshStart1   = 0x94
+	meshStart2   = 0xc3
+	meshMaxFrame = 512
+)
+
+// Field numbers from meshtastic/protobufs (mesh.proto, telemetry.proto, portnums.proto)
+const (
+	meshFromRadioPacket   protowire.Number = 2
+	meshFromRadioMyInfo   protowire.Number = 3
+	meshFromRadioNodeInfo protowire.Number = 4
+	meshToRadioWantConfig protowire.Number = 3
+	meshToRadioHeartbeat  protowire.Number = 7
+
+	meshPortText      = 1
+	meshPortPosition  = 3
+	meshPortNodeInfo  = 4
+	meshPortTelemetry = 67
+)
+
+// meshNode is one entry of the node database.
+type meshNode struct {
+	id, longName, shortName string
+	lastHeard               time.Time
+	metrics                 map[string]interface{}
+	packets                 int64
+}
+
+// meshRadio keeps one connection to a Meshtastic device and the node
+// database built from its config dump and live packets.
+type meshRadio struct {
+	kind, addr string // "serial" or "tcp"
+
+	mu        sync.Mutex
+	nodes     map[uint32]*meshNode
+	myNode    uint32
+	connected bool
+	writeMu   sync.Mutex
+}
+
+var (
+	meshRadiosMu sync.Mutex
+	meshRadios   = make(map[string]*meshRadio)
+	// Auto-detected serial device per instance, so a second port showing up
+	// later doesn't switch the instance to it
+	meshDevices = make(map[string]string)
+)
+
+// MeshtasticCollector reports every node heard within the TTL as its own
+// ship, with battery, environment, position and signal metrics.
+//
+//	--param device=/dev/ttyUSB0   (default: first serial port found)
+//	--param host=192.168.1.50     (TCP / Wi-Fi, port 4403)
+//	--param ttl=3600              (forget nodes not heard for this long)
+//	--param ship_by=id            (id, short_name or long_name)
+func MeshtasticCollector(params map[string]string) ([]map[string]interface{}, error) {
+	kind, addr := "serial", params["device"]
+	if host := params["host"]; host != "" {
+		kind, addr = "tcp", host
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "4403")
+		}
+	}
+	if addr == "" {
+		var err error
+		if addr, err = meshDevice(paramsHash(params)); err != nil {
+			return nil, err
+		}
+	}
+
+	r := getMeshRadio(kind, addr)
+	ttl := time.Duration(paramInt(params, "ttl", 3600)) * time.Second
+	shipBy := params["ship_by"]
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	perNode := make(map[string]map[string]interface{})
+	for num, n := range r.nodes {
+		age := time.Since(n.lastHeard)
+		if n.lastHeard.IsZero() || age > ttl {
+			continue
+		}
+
+		name := n.id
+		switch {
+		case shipBy == "short_name" && n.shortName != "":
+			name = n.shortName
+		case shipBy == "long_name" && n.longName != "":
+			name = n.longName
+		}
+
+		m := map[string]interface{}{
+			"mesh_last_heard_s":  int64(age.Seconds()),
+			"mesh_packets_total": n.packets,
+			"mesh_is_gateway":    boolToInt(num == r.myNode),
+		}
+		for k, v := range n.metrics {
+			m[k] = v
+		}
+		perNode[name] = m
+	}
+
+	summary := map[string]interface{}{
+		"mesh_connected": boolToInt(r.connected),
+		"mesh_nodes":     int64(len(perNode)),
+	}
+	return fanOut(params, "node_mode", summary, perNode), nil
+}
+
+// meshDevice returns the serial port the instance picked on its first tick.
+func meshDevice(key string) (string, error) {
+	meshRadiosMu.Lock()
+	defer meshRadiosMu.Unlock()
+
+	if dev, ok := meshDevices[key]; ok {
+		return dev, nil
+	}
+	ports, err := serial.GetPortsList()
+	if err != nil || len(ports) == 0 {
+		return "", fmt.Errorf("no serial port found; set 'device' or 'host'")
+	}
+	meshDevices[key] = ports[0]
+	return ports[0], nil
+}
+
+func getMeshRadio(kind, addr string) *meshRadio {
+	meshRadiosMu.Lock()
+	defer meshRadiosMu.Unlock()
+
+	key := kind + "://" + addr
+	if r, ok := meshRadios[key]; ok {
+		return r
+	}
+	r := &meshRadio{kind: kind, addr: addr, nodes: make(map[uint32]*meshNode)}
+	meshRadios[key] = r
+	go r.run()
+	return r
+}
+
+func (r *meshRadio) setConnected(c bool) {
+	r.mu.Lock()
+	r.connected = c
+	r.mu.Unlock()
+}
+
+// run reads forever, reconnecting with backoff. The node database survives
+// reconnects; the config dump on reconnect refreshes it.
+func (r *meshRadio) run() {
+	backoff := time.Second
+	for {
+		conn, err := r.open()
+		if err != nil {
+			r.setConnected(false)
+			time.Sleep(backoff)
+			backoff = min(backoff*2, 30*time.Second)
+			continue
+		}
+		backoff = time.Second
+
+		err = r.session(conn)
+		conn.Close()
+		r.setConnected(false)
+		log.Printf("⚠️ Meshtastic %s lost: %v", r.addr, err)
+		time.Sleep(time.Second)
+	}
+}
+
+func (r *meshRadio) open() (io.ReadWriteCloser, error) {
+	if r.kind == "serial" {
+		return serial.Open(r.addr, &serial.Mode{BaudRate: 115200})
+	}
+	return net.DialTimeout("tcp", r.addr, 5*time.Second)
+}
+
+func (r *meshRadio) session(conn io.ReadWriteCloser) error {
+	// Wake a sleeping serial device, then ask for the full config + node DB
+	if r.kind == "serial" {
+		wake := make([]byte, 32)
+		for i := range wake {
+			wake[i] = meshStart2
+		}
+		conn.Write(wake)
+		time.Sleep(100 * time.Millisecond)
+	}
+	want := protowire.AppendTag(nil, meshToRadioWantConfig, protowire.VarintType)
+	want = protowire.AppendVarint(want, uint64(rand.Uint32()))
+	if err := r.send(conn, want); err != nil {
+		return err
+	}
+	r.setConnected(true)
+
+	// Firmware drops idle API clients; keep the session alive
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		t := time.NewTicker(5 * time.Minute)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				hb := protowire.AppendTag(nil, meshToRadioHeartbeat, protowire.BytesType)
+				if err := r.send(conn, protowire.AppendBytes(hb, nil)); err != nil {
+					conn.Close() // unblocks the reader so we reconnect
+					return
+				}
+			}
+		}
+	}()
+
+	br := bufio.NewReader(conn)
+	for {
+		frame, err := readMeshFrame(br)
+		if err != nil {
+			return err
+		}
+		msg, err := parsePB(frame)
+		if err != nil {
+			continue
+		}
+		r.mu.Lock()
+		r.handle(msg)
+		r.mu.Unlock()
+	}
+}
+
+func (r *meshRadio) send(w io.Writer, payload []byte) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	hdr := []byte{meshStart1, meshStart2, 0, 0}
+	binary.BigEndian.PutUint16(hdr[2:], uint16(len(payload)))
+	_, err := w.Write(append(hdr, payload...))
+	return err
+}
+
+// readMeshFrame skips log output until a frame header and returns its payload.
+func readMeshFrame(br *bufio.Reader) ([]byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b != meshStart1 {
+			continue
+		}
+		if b, err = br.ReadByte(); err != nil {
+			return nil, err
+		}
+		if b != meshStart2 {
+			br.UnreadByte() // might be the start of the real header
+			continue
+		}
+
+		var size [2]byte
+		if _, err := io.ReadFull(br, size[:]); err != nil {
+			return nil, err
+		}
+		n := binary.BigEndian.Uint16(size[:])
+		if n > meshMaxFrame {
+			continue // corrupt header, resync
+		}
+		frame := make([]byte, n)
+		if _, err := io.ReadFull(br, frame); err != nil {
+			return nil, err
+		}
+		return frame, nil
+	}
+}
+
+// handle applies one FromRadio message to the node database.
+func (r *meshRadio) handle(msg pbMessage) {
+	switch {
+	case msg.has(meshFromRadioMyInfo):
+		r.myNode = uint32(msg.message(meshFromRadioMyInfo).uint(1))
+
+	case msg.has(meshFromRadioNodeInfo):
+		info := msg.message(meshFromRadioNodeInfo)
+		n := r.node(uint32(info.uint(1)))
+		if info.has(2) {
+			n.setUser(info.message(2))
+		}
+		if info.has(3) {
+			n.setPosition(info.message(3))
+		}
+		if info.has(4) {
+			n.metrics["mesh_snr"] = info.float32(4)
+		}
+		if info.has(6) {
+			n.setDeviceMetrics(info.message(6))
+		}
+		if info.has(9) {
+			n.metrics["mesh_hops_away"] = info.int(9)
+		}
+		// last_heard is the device's clock (epoch seconds)
+		if heard := info.uint(5); heard > 0 {
+			n.lastHeard = time.Unix(int64(heard), 0)
+		}
+
+	case msg.has(meshFromRadioPacket):
+		r.handlePacket(msg.message(meshFromRadioPacket))
+	}
+}
+
+// handlePacket applies a received MeshPacket (only decoded ones; we can't
+// read channels the device doesn't have keys for).
+func (r *meshRadio) handlePacket(p pbMessage) {
+	from := uint32(p.uint(1))
+	if from == 0 || !p.has(4) {
+		return
+	}
+	n := r.node(from)
+	n.lastHeard = time.Now()
+	n.packets++
+
+	if p.has(8) {
+		n.metrics["mesh_snr"] = p.float32(8)
+	}
+	if p.has(12) {
+		n.metrics["mesh_rssi"] = int64(int32(p.uint(12)))
+	}
+	// Hops travelled = hop_start - hop_limit (firmware 2.3+)
+	if start := p.int(15); start > 0 {
+		n.metrics["mesh_hops_away"] = start - p.int(9)
+	}
+
+	data := p.message(4)
+	payload, _ := data.last(2)
+	port := data.int(1)
+	if port == meshPortText {
+		count, _ := n.metrics["mesh_text_messages"].(int64)
+		n.metrics["mesh_text_messages"] = count + 1
+		return
+	}
+
+	body, err := parsePB(payload.bytes)
+	if err != nil {
+		return
+	}
+	switch port {
+	case meshPortNodeInfo:
+		n.setUser(body)
+	case meshPortPosition:
+		n.setPosition(body)
+	case meshPortTelemetry:
+		if body.has(2) {
+			n.setDeviceMetrics(body.message(2))
+		}
+		if body.has(3) {
+			n.setEnvironment(body.message(3))
+		}
+	}
+}
+
+func (r *meshRadio) node(num uint32) *meshNode {
+	n, ok := r.nodes[num]
+	if !ok {
+		n = &meshNode{id: fmt.Sprintf("!%08x", num), metrics: make(map[string]interface{})}
+		r.nodes[num] = n
+	}
+	return n
+}
+
+func (n *meshNode) setUser(u pbMessage) {
+	if id := u.string(1); strings.HasPrefix(id, "!") {
+		n.id = id
+	}
+	if name := u.string(2); name != "" {
+		n.longName = name
+	}
+	if name := u.string(3); name != "" {
+		n.shortName = name
+	}
+}
+
+func (n *meshNode) setPosition(p pbMessage) {
+	// latitude_i / longitude_i are sfixed32 in 1e-7 degrees; 0,0 means unknown
+	lat, lon := int32(uint32(p.uint(1))), int32(uint32(p.uint(2)))
+	if lat == 0 && lon == 0 {
+		return
+	}
+	n.metrics["mesh_latitude"] = float64(lat) / 1e7
+	n.metrics["mesh_longitude"] = float64(lon) / 1e7
+	if p.has(3) {
+		n.metrics["mesh_altitude"] = int64(int32(p.uint(3)))
+	}
+}
+
+func (n *meshNode) setDeviceMetrics(d pbMessage) {
+	if d.has(1) {
+		n.metrics["mesh_battery_pct"] = d.int(1) // 101 = externally powered
+	}
+	if d.has(2) {
+		n.metrics["mesh_voltage"] = d.float32(2)
+	}
+	if d.has(3) {
+		n.metrics["mesh_channel_util_pct"] = d.float32(3)
+	}
+	if d.has(4) {
+		n.metrics["mesh_air_util_tx_pct"] = d.float32(4)
+	}
+	if d.has(5) {
+		n.metrics["mesh_uptime_s"] = d.int(5)
+	}
+}
+
+func (n *meshNode) setEnvironment(e pbMessage) {
+	fields := []struct {
+		num  protowire.Number
+		name string
+	}{
+		{1, "mesh_temperature_c"},
+		{2, "mesh_humidity_pct"},
+		{3, "mesh_pressure_hpa"},
+		{4, "mesh_gas_resistance"},
+		{5, "mesh_env_voltage"},
+		{6, "mesh_env_current"},
+		{9, "mesh_lux"},
+	}
+	for _, f := range fields {
+		if e.has(f.num) {
+			setFinite(n.metrics, f.name, e.float32(f.num))
+		}
+	}
+	if e.has(7) {
+		n.metrics["mesh_iaq"] = e.int(7)
+	}
+}
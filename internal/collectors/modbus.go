@@ -0,0 +1,393 @@
+package collectors
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// modbusRegister is one entry of the register map.
+type modbusRegister struct {
+	Name    string  `json:"name"`
+	Table   string  `json:"table"`   // holding (default), input, coil, discrete
+	Address uint16  `json:"address"` // 0-based protocol address
+	Type    string  `json:"type"`    // int16, uint16 (default), int32, uint32, float32, int64, uint64, float64, bool
+	Order   string  `json:"order"`   // abcd (default), cdab, badc, dcba
+	Scale   float64 `json:"scale"`   // 0 = 1
+	Offset  float64 `json:"offset"`
+
+	fc    byte
+	count uint16 // registers (or bits) occupied
+}
+
+// ModbusCollector reads a register map from one or more Modbus slaves over
+// TCP or serial RTU. Each register is decoded, then value*scale+offset is
+// reported under its name.
+//
+//	--param address=192.168.1.50:502                   (Modbus TCP)
+//	--param device=/dev/ttyUSB0 --param baud=9600      (RTU; parity=N|E|O, stop_bits=1|2)
+//	--param slaves=1,2 --param ship.1=meter --param ship.2=plc
+//	--param register.voltage=input:0:float32:cdab:1:0  (table:address:type[:order[:scale[:offset]]])
+//	--param register_map=/etc/lighthouse/meter.json    (JSON list of registers)
+//
+// Addresses are 0-based protocol addresses (holding register 40001 is 0).
+// With one slave and no ship.<id> the values go to the instance's ship;
+// otherwise each slave is its own ship (slave_mode=cargo to merge).
+func ModbusCollector(params map[string]string) ([]map[string]interface{}, error) {
+	regs, err := modbusRegisters(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var units []byte
+	for _, s := range splitList(params["slaves"] + "," + params["slave"]) {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > 255 {
+			return nil, fmt.Errorf("invalid slave id %q", s)
+		}
+		units = append(units, byte(n))
+	}
+	if len(units) == 0 {
+		units = []byte{1}
+	}
+
+	timeout := paramMillis(params, "timeout_ms", time.Second)
+	var client *modbusClient
+	var release func(ok bool)
+	switch {
+	case params["device"] != "":
+		mode, err := modbusSerialMode(params)
+		if err != nil {
+			return nil, err
+		}
+		client, release, err = acquireModbusRTU(params["device"], mode, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", params["device"], err)
+		}
+	case params["address"] != "":
+		client, err = dialModbusTCP(params["address"], timeout)
+		if err != nil {
+			return nil, fmt.Errorf("connect %s: %w", params["address"], err)
+		}
+		release = func(bool) { client.Close() }
+	default:
+		return nil, fmt.Errorf("missing 'address' or 'device' param")
+	}
+
+	summary := make(map[string]interface{})
+	perUnit := make(map[string]map[string]interface{})
+	up := int64(0)
+	defer func() { release(up > 0) }()
+	start := time.Now()
+
+	for _, unit := range units {
+		m, errs := modbusReadUnit(client, unit, regs)
+		ok := errs < len(regs)
+		m["modbus_up"] = boolToInt(ok)
+		m["modbus_errors"] = int64(errs)
+		if ok {
+			up++
+		}
+
+		name, named := params["ship."+strconv.Itoa(int(unit))]
+		if len(units) == 1 && !named {
+			for k, v := range m {
+				summary[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = "slave" + strconv.Itoa(int(unit))
+		}
+		perUnit[name] = m
+	}
+
+	if up == 0 {
+		return nil, fmt.Errorf("no Modbus slave answered")
+	}
+	if len(perUnit) > 0 {
+		summary["modbus_slaves"] = int64(len(units))
+		summary["modbus_slaves_up"] = up
+	}
+	summary["modbus_read_ms"] = float64(time.Since(start).Microseconds()) / 1000
+	return fanOut(params, "slave_mode", summary, perUnit), nil
+}
+
+// modbusReadUnit reads all registers from one slave, batching neighbouring
+// addresses into one request. Returns the values and the number of registers
+// that could not be read.
+func modbusReadUnit(c *modbusClient, unit byte, regs []modbusRegister) (map[string]interface{}, int) {
+	out := make(map[string]interface{}, len(regs)+3)
+	errs := 0
+
+	for _, batch := range modbusBatches(regs) {
+		first := batch[0].Address
+		last := first
+		for _, r := range batch {
+			last = max(last, r.Address+r.count-1)
+		}
+
+		data, err := c.read(unit, batch[0].fc, first, last-first+1)
+		var exc *modbusException
+		isExc := errors.As(err, &exc)
+		if isExc && len(batch) > 1 {
+			// Some devices reject reads that span unmapped addresses; go one by one
+			for _, r := range batch {
+				if d, err := c.read(unit, r.fc, r.Address, r.count); err == nil {
+					modbusDecode(r, d, 0, out)
+				} else {
+					errs++
+				}
+			}
+			continue
+		}
+		if err != nil {
+			errs += len(batch)
+			if !isExc {
+				// Timeouts: the slave is likely gone, don't wait on every batch
+				return out, len(regs)
+			}
+			continue
+		}
+		for _, r := range batch {
+			if !modbusDecode(r, data, r.Address-first, out) {
+				errs++
+			}
+		}
+	}
+	return out, errs
+}
+
+// modbusBatches groups registers of the same table whose addresses are close
+// enough to be read in one request.
+func modbusBatches(regs []modbusRegister) [][]modbusRegister {
+	sorted := append([]modbusRegister(nil), regs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].fc != sorted[j].fc {
+			return sorted[i].fc < sorted[j].fc
+		}
+		return sorted[i].Address < sorted[j].Address
+	})
+
+	var batches [][]modbusRegister
+	var cur []modbusRegister
+	var end int // one past the last address in cur
+	for _, r := range sorted {
+		limit, gap := 125, 16 // registers per request, max hole to read through
+		if r.fc <= modbusReadDiscrete {
+			limit, gap = 2000, 256
+		}
+		rEnd := int(r.Address) + int(r.count)
+		if len(cur) > 0 && r.fc == cur[0].fc && int(r.Address) <= end+gap && rEnd-int(cur[0].Address) <= limit {
+			cur = append(cur, r)
+			end = max(end, rEnd)
+			continue
+		}
+		if len(cur) > 0 {
+			batches = append(batches, cur)
+		}
+		cur, end = []modbusRegister{r}, rEnd
+	}
+	if len(cur) > 0 {
+		batches = append(batches, cur)
+	}
+	return batches
+}
+
+// modbusDecode stores the value of r found at register (or bit) offset off
+// of a response. Returns false if the response is too short.
+func modbusDecode(r modbusRegister, data []byte, off uint16, out map[string]interface{}) bool {
+	if r.fc <= modbusReadDiscrete {
+		i := int(off)
+		if i/8 >= len(data) {
+			return false
+		}
+		out[r.Name] = int64(data[i/8] >> (i % 8) & 1)
+		return true
+	}
+
+	lo, hi := int(off)*2, int(off+r.count)*2
+	if hi > len(data) {
+		return false
+	}
+	b := append([]byte(nil), data[lo:hi]...)
+	order := strings.ToLower(r.Order)
+	if order == "badc" || order == "dcba" { // bytes swapped within each word
+		for i := 0; i+1 < len(b); i += 2 {
+			b[i], b[i+1] = b[i+1], b[i]
+		}
+	}
+	if order == "cdab" || order == "dcba" { // low word first
+		for i, j := 0, len(b)-2; i < j; i, j = i+2, j-2 {
+			b[i], b[i+1], b[j], b[j+1] = b[j], b[j+1], b[i], b[i+1]
+		}
+	}
+
+	var v float64
+	switch r.Type {
+	case "int16":
+		v = float64(int16(binary.BigEndian.Uint16(b)))
+	case "uint16", "":
+		v = float64(binary.BigEndian.Uint16(b))
+	case "int32":
+		v = float64(int32(binary.BigEndian.Uint32(b)))
+	case "uint32":
+		v = float64(binary.BigEndian.Uint32(b))
+	case "float32":
+		v = float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+	case "int64":
+		v = float64(int64(binary.BigEndian.Uint64(b)))
+	case "uint64":
+		v = float64(binary.BigEndian.Uint64(b))
+	case "float64":
+		v = math.Float64frombits(binary.BigEndian.Uint64(b))
+	}
+
+	scale := r.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	if r.Offset == 0 && scale == 1 && !strings.HasPrefix(r.Type, "float") {
+		out[r.Name] = int64(v)
+		return true
+	}
+	setFinite(out, r.Name, v*scale+r.Offset)
+	return true
+}
+
+// modbusRegisters collects register.<name> params and the register_map file.
+func modbusRegisters(params map[string]string) ([]modbusRegister, error) {
+	var regs []modbusRegister
+	if path := params["register_map"]; path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &regs); err != nil {
+			return nil, fmt.Errorf("register_map %s: %w", path, err)
+		}
+	}
+
+	for k, spec := range params {
+		name, ok := strings.CutPrefix(k, "register.")
+		if !ok {
+			continue
+		}
+		parts := strings.Split(spec, ":")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("register %s: want table:address[:type[:order[:scale[:offset]]]]", name)
+		}
+		addr, err := strconv.ParseUint(parts[1], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("register %s: bad address %q", name, parts[1])
+		}
+		r := modbusRegister{Name: name, Table: parts[0], Address: uint16(addr)}
+		if len(parts) > 2 {
+			r.Type = parts[2]
+		}
+		if len(parts) > 3 {
+			r.Order = parts[3]
+		}
+		if len(parts) > 4 && parts[4] != "" {
+			if r.Scale, err = strconv.ParseFloat(parts[4], 64); err != nil {
+				return nil, fmt.Errorf("register %s: bad scale %q", name, parts[4])
+			}
+		}
+		if len(parts) > 5 && parts[5] != "" {
+			if r.Offset, err = strconv.ParseFloat(parts[5], 64); err != nil {
+				return nil, fmt.Errorf("register %s: bad offset %q", name, parts[5])
+			}
+		}
+		regs = append(regs, r)
+	}
+
+	if len(regs) == 0 {
+		return nil, fmt.Errorf("no registers configured (register.<name> or register_map)")
+	}
+	defOrder := params["byte_order"]
+	for i := range regs {
+		r := &regs[i]
+		if r.Order == "" {
+			r.Order = defOrder
+		}
+		if err := r.resolve(); err != nil {
+			return nil, fmt.Errorf("register %s: %w", r.Name, err)
+		}
+	}
+	return regs, nil
+}
+
+// resolve validates a register and fills its function code and width.
+func (r *modbusRegister) resolve() error {
+	if r.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	switch strings.ToLower(r.Table) {
+	case "holding", "":
+		r.fc = modbusReadHolding
+	case "input":
+		r.fc = modbusReadInput
+	case "coil", "coils":
+		r.fc, r.count, r.Type = modbusReadCoils, 1, "bool"
+		return nil
+	case "discrete", "discrete_input":
+		r.fc, r.count, r.Type = modbusReadDiscrete, 1, "bool"
+		return nil
+	default:
+		return fmt.Errorf("unknown table %q", r.Table)
+	}
+
+	r.Type = strings.ToLower(r.Type)
+	switch r.Type {
+	case "int16", "uint16", "":
+		r.count = 1
+	case "int32", "uint32", "float32":
+		r.count = 2
+	case "int64", "uint64", "float64":
+		r.count = 4
+	default:
+		return fmt.Errorf("unknown type %q", r.Type)
+	}
+
+	switch strings.ToLower(r.Order) {
+	case "", "abcd", "cdab", "badc", "dcba":
+	default:
+		return fmt.Errorf("unknown order %q (abcd, cdab, badc, dcba)", r.Order)
+	}
+	if int(r.Address)+int(r.count) > 65536 {
+		return fmt.Errorf("address out of range")
+	}
+	return nil
+}
+
+func modbusSerialMode(params map[string]string) (*serial.Mode, error) {
+	mode := &serial.Mode{BaudRate: paramInt(params, "baud", 9600), DataBits: 8}
+	switch strings.ToUpper(params["parity"]) {
+	case "", "N", "NONE":
+		mode.Parity = serial.NoParity
+	case "E", "EVEN":
+		mode.Parity = serial.EvenParity
+	case "O", "ODD":
+		mode.Parity = serial.OddParity
+	default:
+		return nil, fmt.Errorf("invalid parity %q", params["parity"])
+	}
+	switch params["stop_bits"] {
+	case "", "1":
+		mode.StopBits = serial.OneStopBit
+	case "2":
+		mode.StopBits = serial.TwoStopBits
+	default:
+		return nil, fmt.Errorf("invalid stop_bits %q", params["stop_bits"])
+	}
+	return mode, nil
+}
@@ -0,0 +1,266 @@
+package collectors
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// Modbus function codes we use (reads only)
+const (
+	modbusReadCoils    = 0x01
+	modbusReadDiscrete = 0x02
+	modbusReadHolding  = 0x03
+	modbusReadInput    = 0x04
+)
+
+// modbusClient speaks Modbus TCP (MBAP header) or RTU (CRC16) on one connection.
+type modbusClient struct {
+	conn    io.ReadWriteCloser
+	rtu     bool
+	tid     uint16
+	timeout time.Duration
+}
+
+func dialModbusTCP(addr string, timeout time.Duration) (*modbusClient, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "502")
+	}
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &modbusClient{conn: conn, timeout: timeout}, nil
+}
+
+func openModbusRTU(device string, mode *serial.Mode, timeout time.Duration) (*modbusClient, error) {
+	port, err := serial.Open(device, mode)
+	if err != nil {
+		return nil, err
+	}
+	if err := port.SetReadTimeout(timeout); err != nil {
+		port.Close()
+		return nil, err
+	}
+	return &modbusClient{conn: port, rtu: true, timeout: timeout}, nil
+}
+
+func (c *modbusClient) Close() error {
+	return c.conn.Close()
+}
+
+// setTimeout changes the per-request timeout (instances sharing a port may
+// configure different ones).
+func (c *modbusClient) setTimeout(timeout time.Duration) error {
+	c.timeout = timeout
+	if p, ok := c.conn.(serial.Port); ok {
+		return p.SetReadTimeout(timeout)
+	}
+	return nil
+}
+
+// modbusException is an exception response from the slave: it is alive but
+// refused the request (e.g. 2 = illegal data address).
+type modbusException struct {
+	fc, code byte
+}
+
+func (e *modbusException) Error() string {
+	return fmt.Sprintf("modbus: exception %d for function %d", e.code, e.fc)
+}
+
+// One open port per serial device, reused across ticks like the MQTT
+// clients; instances polling slaves on the same bus take turns through mu.
+type modbusPort struct {
+	mu     sync.Mutex
+	mode   serial.Mode
+	client *modbusClient
+}
+
+var (
+	modbusPortsMu sync.Mutex
+	modbusPorts   = make(map[string]*modbusPort) // keyed by device path
+)
+
+// acquireModbusRTU returns the client for a serial device, opening the port
+// on first use, and holds it until release is called. release(false) closes
+// the port so the next tick opens it again (e.g. after a USB adapter was
+// replugged).
+func acquireModbusRTU(device string, mode *serial.Mode, timeout time.Duration) (*modbusClient, func(ok bool), error) {
+	modbusPortsMu.Lock()
+	p, ok := modbusPorts[device]
+	if !ok {
+		p = &modbusPort{mode: *mode}
+		modbusPorts[device] = p
+	}
+	modbusPortsMu.Unlock()
+
+	if p.mode != *mode {
+		return nil, nil, fmt.Errorf("already open with different baud/parity/stop_bits")
+	}
+
+	p.mu.Lock()
+	if p.client == nil {
+		c, err := openModbusRTU(device, mode, timeout)
+		if err != nil {
+			p.mu.Unlock()
+			return nil, nil, err
+		}
+		p.client = c
+	} else if err := p.client.setTimeout(timeout); err != nil {
+		p.client.Close()
+		p.client = nil
+		p.mu.Unlock()
+		return nil, nil, err
+	}
+
+	release := func(ok bool) {
+		if !ok {
+			p.client.Close()
+			p.client = nil
+		}
+		p.mu.Unlock()
+	}
+	return p.client, release, nil
+}
+
+// read issues a read request and returns the data bytes of the response.
+func (c *modbusClient) read(unit, fc byte, addr, qty uint16) ([]byte, error) {
+	pdu := []byte{fc, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(pdu[1:], addr)
+	binary.BigEndian.PutUint16(pdu[3:], qty)
+
+	if nc, ok := c.conn.(net.Conn); ok {
+		nc.SetDeadline(time.Now().Add(c.timeout))
+	}
+
+	var resp []byte
+	var err error
+	if c.rtu {
+		resp, err = c.roundTripRTU(unit, pdu)
+	} else {
+		resp, err = c.roundTripTCP(unit, pdu)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// resp is the response PDU: fc, byte count, data (or fc|0x80, exception code)
+	if len(resp) < 2 {
+		return nil, fmt.Errorf("modbus: short response")
+	}
+	if resp[0] == fc|0x80 {
+		return nil, &modbusException{fc: fc, code: resp[1]}
+	}
+	if resp[0] != fc || int(resp[1]) != len(resp)-2 {
+		return nil, fmt.Errorf("modbus: malformed response")
+	}
+	return resp[2:], nil
+}
+
+func (c *modbusClient) roundTripTCP(unit byte, pdu []byte) ([]byte, error) {
+	c.tid++
+	req := make([]byte, 7, 7+len(pdu))
+	binary.BigEndian.PutUint16(req[0:], c.tid)
+	binary.BigEndian.PutUint16(req[4:], uint16(len(pdu)+1))
+	req[6] = unit
+	if _, err := c.conn.Write(append(req, pdu...)); err != nil {
+		return nil, err
+	}
+
+	for {
+		hdr := make([]byte, 7)
+		if _, err := io.ReadFull(c.conn, hdr); err != nil {
+			return nil, err
+		}
+		n := int(binary.BigEndian.Uint16(hdr[4:]))
+		if n < 2 || n > 260 {
+			return nil, fmt.Errorf("modbus: bad MBAP length %d", n)
+		}
+		body := make([]byte, n-1)
+		if _, err := io.ReadFull(c.conn, body); err != nil {
+			return nil, err
+		}
+		// Skip late answers to earlier (timed out) requests
+		if binary.BigEndian.Uint16(hdr[0:]) == c.tid {
+			return body, nil
+		}
+	}
+}
+
+func (c *modbusClient) roundTripRTU(unit byte, pdu []byte) ([]byte, error) {
+	// 3.5 character silence between frames; a few ms covers 9600 baud and up
+	time.Sleep(5 * time.Millisecond)
+	// The port stays open across ticks: drop a late reply to a timed out request
+	if p, ok := c.conn.(serial.Port); ok {
+		p.ResetInputBuffer()
+	}
+
+	frame := append([]byte{unit}, pdu...)
+	frame = binary.LittleEndian.AppendUint16(frame, modbusCRC(frame))
+	if _, err := c.conn.Write(frame); err != nil {
+		return nil, err
+	}
+
+	// unit, fc, then byte count (or exception code)
+	head, err := c.readRTU(3)
+	if err != nil {
+		return nil, err
+	}
+	rest := 2 // exception: just the CRC left
+	if head[1]&0x80 == 0 {
+		rest = int(head[2]) + 2
+	}
+	tail, err := c.readRTU(rest)
+	if err != nil {
+		return nil, err
+	}
+	resp := append(head, tail...)
+
+	n := len(resp)
+	if modbusCRC(resp[:n-2]) != binary.LittleEndian.Uint16(resp[n-2:]) {
+		return nil, fmt.Errorf("modbus: CRC mismatch")
+	}
+	if resp[0] != unit {
+		return nil, fmt.Errorf("modbus: reply from unit %d, expected %d", resp[0], unit)
+	}
+	return resp[1 : n-2], nil
+}
+
+// readRTU reads exactly n bytes; the serial port returns 0 bytes on timeout.
+func (c *modbusClient) readRTU(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	got := 0
+	for got < n {
+		m, err := c.conn.Read(buf[got:])
+		if err != nil {
+			return nil, err
+		}
+		if m == 0 {
+			return nil, fmt.Errorf("modbus: timeout")
+		}
+		got += m
+	}
+	return buf, nil
+}
+
+// modbusCRC is CRC-16/MODBUS (poly 0xA001 reflected, init 0xFFFF).
+func modbusCRC(b []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, v := range b {
+		crc ^= uint16(v)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xa001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
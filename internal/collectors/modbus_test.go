@@ -0,0 +1,245 @@
+package collectors
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeModbusSlave is a Modbus TCP slave over a sparse register space.
+// Reading any address that isn't set answers exception 2 (illegal data
+// address), like devices with holes in their register map.
+type fakeModbusSlave struct {
+	mu       sync.Mutex
+	holding  map[uint16]uint16
+	input    map[uint16]uint16
+	coils    map[uint16]bool
+	requests []modbusTestRequest
+}
+
+type modbusTestRequest struct {
+	fc        byte
+	addr, qty uint16
+}
+
+func startFakeModbusSlave(t *testing.T, s *fakeModbusSlave) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func (s *fakeModbusSlave) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		// MBAP: transaction, protocol, length, unit; then fc, address, quantity
+		req := make([]byte, 12)
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		fc := req[7]
+		addr := binary.BigEndian.Uint16(req[8:])
+		qty := binary.BigEndian.Uint16(req[10:])
+
+		pdu := s.answer(fc, addr, qty)
+		resp := append([]byte(nil), req[:7]...)
+		binary.BigEndian.PutUint16(resp[4:], uint16(len(pdu)+1))
+		conn.Write(append(resp, pdu...))
+	}
+}
+
+func (s *fakeModbusSlave) answer(fc byte, addr, qty uint16) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, modbusTestRequest{fc, addr, qty})
+
+	exception := []byte{fc | 0x80, 2}
+	switch fc {
+	case modbusReadCoils:
+		data := make([]byte, (qty+7)/8)
+		for i := uint16(0); i < qty; i++ {
+			on, ok := s.coils[addr+i]
+			if !ok {
+				return exception
+			}
+			if on {
+				data[i/8] |= 1 << (i % 8)
+			}
+		}
+		return append([]byte{fc, byte(len(data))}, data...)
+
+	case modbusReadHolding, modbusReadInput:
+		regs := s.holding
+		if fc == modbusReadInput {
+			regs = s.input
+		}
+		data := make([]byte, 0, qty*2)
+		for i := uint16(0); i < qty; i++ {
+			v, ok := regs[addr+i]
+			if !ok {
+				return exception
+			}
+			data = binary.BigEndian.AppendUint16(data, v)
+		}
+		return append([]byte{fc, byte(len(data))}, data...)
+	}
+	return []byte{fc | 0x80, 1} // illegal function
+}
+
+func (s *fakeModbusSlave) requestsFor(fc byte) []modbusTestRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []modbusTestRequest
+	for _, r := range s.requests {
+		if r.fc == fc {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func TestModbusCollectorTCP(t *testing.T) {
+	// 1234.5 as float32 is 0x449A5000: bytes A=44 B=9A C=50 D=00
+	slave := &fakeModbusSlave{
+		holding: map[uint16]uint16{
+			0: 0x449A, 1: 0x5000, // abcd
+			2: 0x5000, 3: 0x449A, // cdab
+			4: 0x9A44, 5: 0x0050, // badc
+			6: 0x0050, 7: 0x9A44, // dcba
+			8: 0xFFFB, 9: 0, // int16 -5
+			10: 0x0001, 11: 0x0002, // uint32 65538
+			// 100 and 110 with a hole between: the batch read fails, single reads work
+			100: 7,
+			110: 9,
+		},
+		input: map[uint16]uint16{0: 2305},
+		coils: map[uint16]bool{0: false, 1: true},
+	}
+	addr := startFakeModbusSlave(t, slave)
+
+	res, err := ModbusCollector(map[string]string{
+		"address":             addr,
+		"register.f_abcd":     "holding:0:float32:abcd",
+		"register.f_cdab":     "holding:2:float32:cdab",
+		"register.f_badc":     "holding:4:float32:badc",
+		"register.f_dcba":     "holding:6:float32:dcba",
+		"register.temp":       "holding:8:int16::0.1:1",
+		"register.energy":     "holding:10:uint32",
+		"register.before":     "holding:100",
+		"register.after":      "holding:110",
+		"register.missing":    "holding:200",
+		"register.voltage":    "input:0:uint16::0.1",
+		"register.relay_off":  "coil:0",
+		"register.relay_on":   "coil:1",
+		"register.unmapped_2": "coil:2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 {
+		t.Fatalf("got %d results, want 1", len(res))
+	}
+
+	assertMetrics(t, res[0], map[string]interface{}{
+		"f_abcd":        1234.5,
+		"f_cdab":        1234.5,
+		"f_badc":        1234.5,
+		"f_dcba":        1234.5,
+		"temp":          0.5, // -5*0.1+1
+		"energy":        int64(65538),
+		"before":        int64(7),
+		"after":         int64(9),
+		"voltage":       230.5,
+		"relay_off":     int64(0),
+		"relay_on":      int64(1),
+		"modbus_up":     int64(1),
+		"modbus_errors": int64(2), // missing, unmapped_2
+	})
+	if _, ok := res[0]["missing"]; ok {
+		t.Error("missing register reported")
+	}
+
+	// Holding 0..11 in one request; 100..110 as a batch, then one by one; 200
+	want := []modbusTestRequest{
+		{modbusReadHolding, 0, 12},
+		{modbusReadHolding, 100, 11},
+		{modbusReadHolding, 100, 1},
+		{modbusReadHolding, 110, 1},
+		{modbusReadHolding, 200, 1},
+	}
+	got := slave.requestsFor(modbusReadHolding)
+	if len(got) != len(want) {
+		t.Fatalf("holding requests = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("holding request %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestModbusCollectorSlaves(t *testing.T) {
+	addr := startFakeModbusSlave(t, &fakeModbusSlave{holding: map[uint16]uint16{0: 42}})
+
+	res, err := ModbusCollector(map[string]string{
+		"address":      addr,
+		"slaves":       "1,2",
+		"ship.1":       "meter",
+		"register.val": "holding:0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ships := make(map[string]map[string]interface{})
+	for _, r := range res {
+		id, _ := r["ship_id"].(string)
+		ships[id] = r
+	}
+	for _, id := range []string{"meter", "slave2"} {
+		if m, ok := ships[id]; !ok || m["val"] != int64(42) {
+			t.Errorf("ship %s = %v, want val 42", id, m)
+		}
+	}
+}
+
+func TestModbusException(t *testing.T) {
+	addr := startFakeModbusSlave(t, &fakeModbusSlave{})
+	c, err := dialModbusTCP(addr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	_, err = c.read(1, modbusReadHolding, 0, 1)
+	var exc *modbusException
+	if !errors.As(err, &exc) || exc.code != 2 || exc.fc != modbusReadHolding {
+		t.Fatalf("err = %v, want exception 2", err)
+	}
+
+	// Nobody answers at all: an error, not an exception
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	dead := ln.Addr().String()
+	ln.Close()
+	if _, err := ModbusCollector(map[string]string{"address": dead, "register.x": "holding:0"}); err == nil {
+		t.Error("expected an error from a closed port")
+	}
+}
@@ -0,0 +1,205 @@
+package collectors
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// mqttInbox keeps the latest value of every field per topic between ticks.
+type mqttInbox struct {
+	mu     sync.Mutex
+	topics map[string]map[string]interface{} // topic -> field ("" = plain payload) -> value
+}
+
+var (
+	mqttInboxesMu sync.Mutex
+	mqttInboxes   = make(map[string]*mqttInbox) // keyed by instance params
+)
+
+// MQTTCollector subscribes to topic filters on a broker and reports the
+// latest value received on each topic since the last tick. Payloads can be
+// a plain number or a JSON object (nested keys are joined with ".").
+//
+// Topic segments map to ship and cargo IDs via templates, where {1} is the
+// first segment, {topic} the whole topic and {key} the JSON field:
+//
+//	--param topics="farm/+/sensors/#"
+//	--param ship_template={2}             (farm/greenhouse1/sensors/temp -> greenhouse1)
+//	--param cargo_template={4}            (... -> temp; JSON fields become temp.<key>)
+//
+// Without a cargo template the cargo ID is the topic with "/" replaced by ".".
+func MQTTCollector(params map[string]string) ([]map[string]interface{}, error) {
+	broker := params["broker"]
+	if broker == "" {
+		broker = "tcp://localhost:1883"
+	}
+	qos := byte(min(max(paramInt(params, "qos", 0), 0), 2))
+	filters := map[string]byte{}
+	for _, t := range splitList(params["topics"]) {
+		filters[t] = qos
+	}
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("missing 'topics' param")
+	}
+
+	key := paramsHash(params)
+	inbox := getMQTTInbox(key)
+	client := getMQTTClient(key, newMQTTOptions(params, broker, params["username"], params["password"]), filters, inbox.receive)
+
+	inbox.mu.Lock()
+	topics := inbox.topics
+	inbox.topics = make(map[string]map[string]interface{})
+	inbox.mu.Unlock()
+
+	shipTmpl, cargoTmpl := params["ship_template"], params["cargo_template"]
+	ships := make(map[string]map[string]interface{})
+	for topic, fields := range topics {
+		segments := strings.Split(topic, "/")
+		ship := expandTopicTemplate(shipTmpl, topic, segments)
+
+		for field, v := range fields {
+			cargo := mqttCargoID(cargoTmpl, topic, segments, field)
+			if cargo == "" {
+				continue
+			}
+
+			if ships[ship] == nil {
+				ships[ship] = make(map[string]interface{})
+			}
+			ships[ship][cargo] = v
+		}
+	}
+
+	results := []map[string]interface{}{}
+	for ship, m := range ships {
+		if ship != "" {
+			m["ship_id"] = params["ship_prefix"] + ship
+		}
+		results = append(results, m)
+	}
+
+	if len(results) == 0 && !client.IsConnectionOpen() {
+		return nil, fmt.Errorf("not connected to %s", broker)
+	}
+	return results, nil
+}
+
+// mqttCargoID names one value. The default is the topic with dots plus the
+// JSON key ("farm.gh1.temp.value"). A plain-number payload has no key, so
+// {key} goes away together with the separator next to it.
+func mqttCargoID(tmpl, topic string, segments []string, field string) string {
+	dotted := strings.ReplaceAll(topic, "/", ".")
+	cargo := dotted
+	if tmpl != "" {
+		cargo = expandTopicTemplate(tmpl, topic, segments)
+	}
+
+	switch {
+	case !strings.Contains(tmpl, "{key}"):
+		if field != "" {
+			cargo += "." + field
+		}
+	case field != "":
+		cargo = strings.ReplaceAll(cargo, "{key}", field)
+	default:
+		for _, sep := range []string{".", "_", "-", "/"} {
+			cargo = strings.ReplaceAll(cargo, sep+"{key}", "")
+			cargo = strings.ReplaceAll(cargo, "{key}"+sep, "")
+		}
+		cargo = strings.ReplaceAll(cargo, "{key}", "")
+		if cargo == "" {
+			cargo = dotted
+		}
+	}
+	return cargo
+}
+
+// expandTopicTemplate fills {topic} and {1}..{n} (n = number of segments).
+func expandTopicTemplate(tmpl, topic string, segments []string) string {
+	if tmpl == "" {
+		return ""
+	}
+	out := strings.ReplaceAll(tmpl, "{topic}", topic)
+	for i := len(segments); i >= 1; i-- { // high indexes first so {1} doesn't eat {12}
+		out = strings.ReplaceAll(out, "{"+strconv.Itoa(i)+"}", segments[i-1])
+	}
+	return out
+}
+
+func getMQTTInbox(key string) *mqttInbox {
+	mqttInboxesMu.Lock()
+	defer mqttInboxesMu.Unlock()
+
+	if in, ok := mqttInboxes[key]; ok {
+		return in
+	}
+	in := &mqttInbox{topics: make(map[string]map[string]interface{})}
+	mqttInboxes[key] = in
+	return in
+}
+
+func (in *mqttInbox) receive(_ mqtt.Client, msg mqtt.Message) {
+	fields := parseMQTTPayload(msg.Payload())
+	if len(fields) == 0 {
+		return
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	latest := in.topics[msg.Topic()]
+	if latest == nil {
+		latest = make(map[string]interface{})
+		in.topics[msg.Topic()] = latest
+	}
+	for k, v := range fields {
+		latest[k] = v
+	}
+}
+
+// parseMQTTPayload accepts a plain number/boolean (field "") or a JSON object.
+func parseMQTTPayload(payload []byte) map[string]interface{} {
+	s := strings.TrimSpace(string(payload))
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		out := map[string]interface{}{}
+		setFinite(out, "", f)
+		return out
+	}
+	switch strings.ToLower(s) {
+	case "true", "on":
+		return map[string]interface{}{"": int64(1)}
+	case "false", "off":
+		return map[string]interface{}{"": int64(0)}
+	}
+
+	var obj map[string]interface{}
+	if json.Unmarshal(payload, &obj) != nil {
+		return nil
+	}
+	out := make(map[string]interface{})
+	flattenJSON("", obj, out)
+	return out
+}
+
+// flattenJSON turns {"env":{"temp":21}} into env.temp=21. Booleans become
+// 0/1; arrays and nulls are skipped.
+func flattenJSON(prefix string, obj map[string]interface{}, out map[string]interface{}) {
+	for k, v := range obj {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch val := v.(type) {
+		case map[string]interface{}:
+			flattenJSON(key, val, out)
+		case bool:
+			out[key] = boolToInt(val)
+		case float64, string:
+			out[key] = val
+		}
+	}
+}
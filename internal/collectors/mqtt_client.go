@@ -0,0 +1,69 @@
+package collectors
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Shared plumbing for the MQTT-based sources (ttn, mqtt).
+
+var (
+	mqttClientsMu sync.Mutex
+	mqttClients   = make(map[string]mqtt.Client) // keyed by instance
+)
+
+// getMQTTClient returns a shared client that reconnects forever and
+// (re)subscribes to topics on every connect. The first call starts it.
+func getMQTTClient(key string, opts *mqtt.ClientOptions, topics map[string]byte, handler mqtt.MessageHandler) mqtt.Client {
+	mqttClientsMu.Lock()
+	defer mqttClientsMu.Unlock()
+
+	if c, ok := mqttClients[key]; ok {
+		return c
+	}
+
+	opts.SetOnConnectHandler(func(c mqtt.Client) {
+		if t := c.SubscribeMultiple(topics, handler); t.Wait() && t.Error() != nil {
+			log.Printf("⚠️ MQTT subscribe failed: %v", t.Error())
+		}
+	})
+	opts.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+		log.Printf("⚠️ MQTT connection lost: %v", err)
+	})
+
+	c := mqtt.NewClient(opts)
+	c.Connect() // ConnectRetry keeps trying in the background
+	mqttClients[key] = c
+	return c
+}
+
+// newMQTTOptions builds client options shared by the MQTT sources.
+// broker is a URL like tcp://host:1883 or ssl://host:8883.
+//
+//	--param client_id=...            (default: lighthouse-<random>)
+//	--param insecure_skip_verify=true
+func newMQTTOptions(params map[string]string, broker, username, password string) *mqtt.ClientOptions {
+	clientID := params["client_id"]
+	if clientID == "" {
+		clientID = fmt.Sprintf("lighthouse-%08x", rand.Uint32())
+	}
+
+	return mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(clientID).
+		SetUsername(username).
+		SetPassword(password).
+		SetKeepAlive(30 * time.Second).
+		SetConnectTimeout(10 * time.Second).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(5 * time.Second).
+		SetMaxReconnectInterval(time.Minute).
+		SetTLSConfig(&tls.Config{InsecureSkipVerify: paramBool(params, "insecure_skip_verify")})
+}
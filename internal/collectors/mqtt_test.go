@@ -0,0 +1,29 @@
+package collectors
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMQTTCargoID(t *testing.T) {
+	const topic = "farm/gh1/sensors/temp"
+	for _, tc := range []struct {
+		tmpl, field, want string
+	}{
+		{"", "", "farm.gh1.sensors.temp"},
+		{"", "value", "farm.gh1.sensors.temp.value"},
+		{"{4}", "", "temp"},
+		{"{4}", "value", "temp.value"},
+		{"{4}.{key}", "value", "temp.value"},
+		{"{4}.{key}", "", "temp"},
+		{"{key}_{4}", "", "temp"},
+		{"{2}.{key}.raw", "", "gh1.raw"},
+		{"{key}", "value", "value"},
+		{"{key}", "", "farm.gh1.sensors.temp"}, // nothing left: the default
+	} {
+		got := mqttCargoID(tc.tmpl, topic, strings.Split(topic, "/"), tc.field)
+		if got != tc.want {
+			t.Errorf("mqttCargoID(%q, field %q) = %q, want %q", tc.tmpl, tc.field, got, tc.want)
+		}
+	}
+}
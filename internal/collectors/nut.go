@@ -0,0 +1,240 @@
+package collectors
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NUT variables we report under fixed names (everything else with vars=all).
+var nutVars = map[string]string{
+	"battery.charge":      "ups_battery_charge_pct",
+	"battery.runtime":     "ups_battery_runtime_s",
+	"battery.voltage":     "ups_battery_voltage_v",
+	"battery.temperature": "ups_battery_temperature_c",
+	"ups.load":            "ups_load_pct",
+	"ups.realpower":       "ups_power_w",
+	"ups.power":           "ups_power_va",
+	"ups.temperature":     "ups_temperature_c",
+	"input.voltage":       "ups_input_voltage_v",
+	"input.frequency":     "ups_input_frequency_hz",
+	"output.voltage":      "ups_output_voltage_v",
+	"output.frequency":    "ups_output_frequency_hz",
+}
+
+// ups.status flags reported as 0/1 (always sent, so alerts can match on 0)
+var nutFlags = []struct{ flag, key string }{
+	{"OL", "ups_online"},
+	{"OB", "ups_on_battery"},
+	{"LB", "ups_low_battery"},
+	{"RB", "ups_replace_battery"},
+	{"CHRG", "ups_charging"},
+	{"OVER", "ups_overload"},
+	{"BYPASS", "ups_bypass"},
+	{"CAL", "ups_calibrating"},
+	{"OFF", "ups_off"},
+}
+
+// NUTCollector queries a Network UPS Tools server (upsd) for every UPS it
+// knows, or the ones listed in ups.
+//
+//	--param address=localhost:3493 --param ups=rack1,rack2
+//	--param username=monitor --param password=secret   (only if upsd requires it)
+//	--param vars=all                                   (also send every numeric variable as nut.<name>)
+//
+// One UPS goes to the instance's ship; several become one ship each
+// (ship_prefix + UPS name), or labeled cargo with ups_mode=cargo.
+func NUTCollector(params map[string]string) ([]map[string]interface{}, error) {
+	addr := params["address"]
+	if addr == "" {
+		addr = "localhost:3493"
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "3493")
+	}
+
+	timeout := paramMillis(params, "timeout_ms", 5*time.Second)
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("connect %s: %w", addr, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	c := &nutConn{conn: conn, r: bufio.NewReader(conn)}
+	defer c.cmd("LOGOUT")
+
+	if u := params["username"]; u != "" {
+		if _, err := c.cmd("USERNAME " + u); err != nil {
+			return nil, err
+		}
+		if _, err := c.cmd("PASSWORD " + params["password"]); err != nil {
+			return nil, err
+		}
+	}
+
+	names := splitList(params["ups"])
+	if len(names) == 0 {
+		rows, err := c.list("UPS")
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			names = append(names, row[0])
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no UPS configured on %s", addr)
+		}
+	}
+
+	all := params["vars"] == "all"
+	summary := make(map[string]interface{})
+	perUPS := make(map[string]map[string]interface{})
+	up := 0
+	var lastErr error
+
+	for _, name := range names {
+		m := make(map[string]interface{})
+		rows, err := c.list("VAR " + name)
+		if err != nil {
+			lastErr = err
+			m["ups_up"] = int64(0)
+		} else {
+			up++
+			m["ups_up"] = int64(1)
+			for _, row := range rows {
+				if len(row) == 3 { // <ups> <var> <value>
+					nutStore(m, row[1], row[2], all)
+				}
+			}
+		}
+
+		if len(names) == 1 {
+			summary = m
+		} else {
+			perUPS[name] = m
+		}
+	}
+
+	if up == 0 {
+		return nil, lastErr
+	}
+	if len(perUPS) > 0 {
+		summary["nut_ups"] = int64(len(names))
+		summary["nut_ups_up"] = int64(up)
+	}
+	return fanOut(params, "ups_mode", summary, perUPS), nil
+}
+
+func nutStore(m map[string]interface{}, name, value string, all bool) {
+	f, err := strconv.ParseFloat(value, 64)
+	isNum := err == nil
+
+	if key, ok := nutVars[name]; ok && isNum {
+		setFinite(m, key, f)
+	}
+	if name == "ups.status" {
+		flags := make(map[string]bool)
+		for _, f := range strings.Fields(value) {
+			flags[f] = true
+		}
+		for _, fl := range nutFlags {
+			m[fl.key] = boolToInt(flags[fl.flag])
+		}
+	}
+
+	// Text variables (model, serial, status words) are left out, like every
+	// other source only sends numbers
+	if all && isNum {
+		setFinite(m, "nut."+name, f)
+	}
+}
+
+// nutConn speaks the line-based upsd protocol.
+type nutConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// cmd sends one command and returns its single-line answer.
+func (c *nutConn) cmd(line string) (string, error) {
+	if _, err := fmt.Fprintf(c.conn, "%s\n", line); err != nil {
+		return "", err
+	}
+	return c.readLine()
+}
+
+func (c *nutConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if code, ok := strings.CutPrefix(line, "ERR "); ok {
+		return "", fmt.Errorf("upsd: %s", strings.ToLower(code))
+	}
+	return line, nil
+}
+
+// list runs "LIST <what>" and returns the fields of each row after the type
+// word, e.g. LIST VAR ups -> [ups, battery.charge, 100].
+func (c *nutConn) list(what string) ([][]string, error) {
+	first, err := c.cmd("LIST " + what)
+	if err != nil {
+		return nil, err
+	}
+	if first != "BEGIN LIST "+what {
+		return nil, fmt.Errorf("upsd: unexpected reply %q", first)
+	}
+
+	var rows [][]string
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "END LIST "+what {
+			return rows, nil
+		}
+		if f := nutFields(line); len(f) > 1 {
+			rows = append(rows, f[1:])
+		}
+	}
+}
+
+// nutFields splits a line on spaces, keeping "quoted values" (with \" and \\
+// escapes) together.
+func nutFields(line string) []string {
+	var fields []string
+	var cur strings.Builder
+	inQuote, escaped, started := false, false, false
+
+	for _, ch := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(ch)
+			escaped = false
+		case ch == '\\' && inQuote:
+			escaped = true
+		case ch == '"':
+			inQuote = !inQuote
+			started = true
+		case ch == ' ' && !inQuote:
+			if started {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				started = false
+			}
+		default:
+			cur.WriteRune(ch)
+			started = true
+		}
+	}
+	if started {
+		fields = append(fields, cur.String())
+	}
+	return fields
+}
@@ -0,0 +1,226 @@
+package collectors
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeUpsd speaks enough of the upsd protocol for the collector. With a
+// username set, LIST commands need USERNAME/PASSWORD first.
+type fakeUpsd struct {
+	username, password string
+	ups                map[string][]string // name -> "var value" lines (value already quoted)
+	broken             map[string]string   // name -> ERR reply for LIST VAR
+
+	mu       sync.Mutex
+	commands []string
+}
+
+func startFakeUpsd(t *testing.T, u *fakeUpsd) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go u.serve(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func (u *fakeUpsd) serve(conn net.Conn) {
+	defer conn.Close()
+
+	var user, pass string
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		line := sc.Text()
+		u.mu.Lock()
+		u.commands = append(u.commands, line)
+		u.mu.Unlock()
+
+		authed := u.username == "" || (user == u.username && pass == u.password)
+		word, arg, _ := strings.Cut(line, " ")
+		switch {
+		case word == "USERNAME":
+			user = arg
+			fmt.Fprint(conn, "OK\n")
+		case word == "PASSWORD":
+			if user != u.username || arg != u.password {
+				fmt.Fprint(conn, "ERR ACCESS-DENIED\n")
+				continue
+			}
+			pass = arg
+			fmt.Fprint(conn, "OK\n")
+		case word == "LOGOUT":
+			fmt.Fprint(conn, "OK Goodbye\n")
+			return
+		case word == "LIST" && !authed:
+			fmt.Fprint(conn, "ERR ACCESS-DENIED\n")
+		case arg == "UPS":
+			fmt.Fprint(conn, "BEGIN LIST UPS\n")
+			for name := range u.ups {
+				fmt.Fprintf(conn, "UPS %s \"Test \\\"UPS\\\" %s\"\n", name, name)
+			}
+			fmt.Fprint(conn, "END LIST UPS\n")
+		case strings.HasPrefix(arg, "VAR "):
+			name := strings.TrimPrefix(arg, "VAR ")
+			if e, ok := u.broken[name]; ok {
+				fmt.Fprintf(conn, "ERR %s\n", e)
+				continue
+			}
+			vars, ok := u.ups[name]
+			if !ok {
+				fmt.Fprint(conn, "ERR UNKNOWN-UPS\n")
+				continue
+			}
+			fmt.Fprintf(conn, "BEGIN LIST VAR %s\n", name)
+			for _, v := range vars {
+				fmt.Fprintf(conn, "VAR %s %s\n", name, v)
+			}
+			fmt.Fprintf(conn, "END LIST VAR %s\n", name)
+		default:
+			fmt.Fprint(conn, "ERR UNKNOWN-COMMAND\n")
+		}
+	}
+}
+
+var testUPSVars = []string{
+	`battery.charge "87"`,
+	`battery.runtime "1260"`,
+	`ups.load "23.5"`,
+	`input.voltage "0.0"`,
+	`ups.status "OB LB"`,
+	`ups.model "Back-UPS \"Pro\" 900"`,
+	`device.mfr "American Power Conversion"`,
+}
+
+func TestNUTCollectorLogin(t *testing.T) {
+	upsd := &fakeUpsd{
+		username: "monitor",
+		password: "s3cret pass",
+		ups:      map[string][]string{"rack1": testUPSVars},
+	}
+	addr := startFakeUpsd(t, upsd)
+
+	res, err := NUTCollector(map[string]string{
+		"address":  addr,
+		"username": "monitor",
+		"password": "s3cret pass",
+		"vars":     "all",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 {
+		t.Fatalf("got %d results, want 1", len(res))
+	}
+	m := res[0]
+	assertMetrics(t, m, map[string]interface{}{
+		"ups_up":                 int64(1),
+		"ups_battery_charge_pct": 87.0,
+		"ups_battery_runtime_s":  1260.0,
+		"ups_load_pct":           23.5,
+		"ups_input_voltage_v":    0.0,
+		"ups_online":             int64(0),
+		"ups_on_battery":         int64(1),
+		"ups_low_battery":        int64(1),
+		"ups_charging":           int64(0),
+		"nut.battery.charge":     87.0,
+	})
+	for k, v := range m {
+		if _, ok := v.(string); ok {
+			t.Errorf("%s = %q: only numbers are sent", k, v)
+		}
+	}
+
+	upsd.mu.Lock()
+	defer upsd.mu.Unlock()
+	want := []string{"USERNAME monitor", "PASSWORD s3cret pass", "LIST UPS", "LIST VAR rack1", "LOGOUT"}
+	if !reflect.DeepEqual(upsd.commands, want) {
+		t.Errorf("commands = %q, want %q", upsd.commands, want)
+	}
+}
+
+func TestNUTCollectorBadPassword(t *testing.T) {
+	addr := startFakeUpsd(t, &fakeUpsd{
+		username: "monitor",
+		password: "right",
+		ups:      map[string][]string{"rack1": testUPSVars},
+	})
+
+	_, err := NUTCollector(map[string]string{"address": addr, "username": "monitor", "password": "wrong"})
+	if err == nil || !strings.Contains(err.Error(), "access-denied") {
+		t.Errorf("err = %v, want access-denied", err)
+	}
+
+	// No login at all: the LIST is refused
+	_, err = NUTCollector(map[string]string{"address": addr})
+	if err == nil || !strings.Contains(err.Error(), "access-denied") {
+		t.Errorf("err = %v, want access-denied", err)
+	}
+}
+
+func TestNUTCollectorSeveralUPS(t *testing.T) {
+	addr := startFakeUpsd(t, &fakeUpsd{
+		ups: map[string][]string{
+			"rack1": {`ups.status "OL CHRG"`, `battery.charge "100"`},
+			"rack2": nil,
+		},
+		broken: map[string]string{"rack2": "DRIVER-NOT-CONNECTED"},
+	})
+
+	res, err := NUTCollector(map[string]string{"address": addr, "ship_prefix": "ups-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMetrics(t, res[0], map[string]interface{}{
+		"nut_ups":    int64(2),
+		"nut_ups_up": int64(1),
+	})
+
+	ships := make(map[string]map[string]interface{})
+	for _, r := range res[1:] {
+		ships[r["ship_id"].(string)] = r
+	}
+	assertMetrics(t, ships["ups-rack1"], map[string]interface{}{
+		"ups_up":       int64(1),
+		"ups_online":   int64(1),
+		"ups_charging": int64(1),
+	})
+	assertMetrics(t, ships["ups-rack2"], map[string]interface{}{"ups_up": int64(0)})
+
+	// Every UPS failing is an error
+	_, err = NUTCollector(map[string]string{"address": addr, "ups": "rack2,nope"})
+	if err == nil || !strings.Contains(err.Error(), "unknown-ups") {
+		t.Errorf("err = %v, want the last upsd error", err)
+	}
+}
+
+func TestNUTFields(t *testing.T) {
+	for line, want := range map[string][]string{
+		`VAR ups battery.charge "100"`:             {"VAR", "ups", "battery.charge", "100"},
+		`VAR ups ups.model "Back-UPS \"Pro\" 900"`: {"VAR", "ups", "ups.model", `Back-UPS "Pro" 900`},
+		`VAR ups path "C:\\ups  two"`:              {"VAR", "ups", "path", `C:\ups  two`},
+		`VAR ups empty ""`:                         {"VAR", "ups", "empty", ""},
+		`UPS  rack1   "desc"`:                      {"UPS", "rack1", "desc"},
+	} {
+		if got := nutFields(line); !reflect.DeepEqual(got, want) {
+			t.Errorf("nutFields(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package collectors
 
 import (
+	"bytes"
 	"encoding/json"
 	"net/http"
 	"time"
@@ -43,14 +44,16 @@ func OllamaCollector(params map[string]string) ([]map[string]interface{}, error)
 	var loadedModels int64 = 0
 	var totalVramBytes int64 = 0
 	var totalSizeBytes int64 = 0
+	perModel := make(map[string]map[string]interface{})
 
 	if err == nil && respPs.StatusCode == 200 {
 		defer respPs.Body.Close()
 		var psData struct {
 			Models []struct {
-				Name      string `json:"name"`
-				Size      int64  `json:"size"`       // Model size on disk
-				SizeVRAM  int64  `json:"size_vram"`  // Critical: VRAM usage
+				Name      string    `json:"name"`
+				Size      int64     `json:"size"`      // Model size on disk
+				SizeVRAM  int64     `json:"size_vram"` // Critical: VRAM usage
+				ExpiresAt time.Time `json:"expires_at"`
 			} `json:"models"`
 		}
 
@@ -59,18 +62,141 @@ func OllamaCollector(params map[string]string) ([]map[string]interface{}, error)
 			for _, m := range psData.Models {
 				totalVramBytes += m.SizeVRAM
 				totalSizeBytes += m.Size
+
+				model := map[string]interface{}{
+					"ollama_model_loaded":       int64(1),
+					"ollama_model_size_mb":      m.Size / 1024 / 1024,
+					"ollama_model_vram_mb":      m.SizeVRAM / 1024 / 1024,
+					"ollama_model_expires_in_s": int64(-1), // -1 = kept loaded forever (keep_alive < 0)
+				}
+				// Share of the model offloaded to the GPU; < 100 means it spills onto the CPU
+				if m.Size > 0 {
+					model["ollama_model_gpu_pct"] = float64(m.SizeVRAM) / float64(m.Size) * 100
+				}
+				// Ollama reports "forever" as a date centuries away
+				if !m.ExpiresAt.IsZero() && m.ExpiresAt.Year() < 2200 {
+					model["ollama_model_expires_in_s"] = max(int64(time.Until(m.ExpiresAt).Seconds()), 0)
+				}
+				perModel[m.Name] = model
 			}
 		}
 	}
 
-	// 4. Return Data
-	return []map[string]interface{}{
-		{
-			"ollama_up":              1,
-			"ollama_latency_ms":      latency,
-			"ollama_models_loaded":   loadedModels,
-			"ollama_vram_usage_mb":   totalVramBytes / 1024 / 1024, // Convert to MB for readability
-			"ollama_model_size_mb":   totalSizeBytes / 1024 / 1024,
-		},
-	}, nil
+	// 4. Installed models (GET /api/tags)
+	var installedModels, installedBytes int64 = -1, 0
+	if respTags, err := client.Get(targetURL + "/api/tags"); err == nil {
+		defer respTags.Body.Close()
+		var tagsData struct {
+			Models []struct {
+				Name string `json:"name"`
+				Size int64  `json:"size"`
+			} `json:"models"`
+		}
+		if respTags.StatusCode == 200 && json.NewDecoder(respTags.Body).Decode(&tagsData) == nil {
+			installedModels = int64(len(tagsData.Models))
+			for _, m := range tagsData.Models {
+				installedBytes += m.Size
+				if _, loaded := perModel[m.Name]; !loaded {
+					perModel[m.Name] = map[string]interface{}{
+						"ollama_model_loaded":  int64(0),
+						"ollama_model_size_mb": m.Size / 1024 / 1024,
+					}
+				}
+			}
+		}
+	}
+
+	summary := map[string]interface{}{
+		"ollama_up":                  1,
+		"ollama_latency_ms":          latency,
+		"ollama_models_loaded":       loadedModels,
+		"ollama_vram_usage_mb":       totalVramBytes / 1024 / 1024, // Convert to MB for readability
+		"ollama_model_size_mb":       totalSizeBytes / 1024 / 1024,
+		"ollama_models_installed":    installedModels,
+		"ollama_models_installed_mb": installedBytes / 1024 / 1024,
+	}
+
+	// 5. Optional synthetic generation (--param probe_model=llama3.2)
+	if model := params["probe_model"]; model != "" {
+		for k, v := range ollamaProbe(targetURL, model, params) {
+			summary[k] = v
+		}
+	}
+
+	// 6. Return Data (per-model ships only when asked for, like docker per_container)
+	if paramBool(params, "per_model") {
+		return fanOut(params, "model_mode", summary, perModel), nil
+	}
+	return []map[string]interface{}{summary}, nil
+}
+
+// ollamaProbe streams a short completion and times it. Time to first token
+// includes loading the model if it wasn't resident.
+func ollamaProbe(baseURL, model string, params map[string]string) map[string]interface{} {
+	result := map[string]interface{}{
+		"ollama_probe_up":         int64(0),
+		"ollama_probe_latency_ms": int64(0),
+	}
+
+	prompt := params["probe_prompt"]
+	if prompt == "" {
+		prompt = "Reply with the single word OK."
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":   model,
+		"prompt":  prompt,
+		"stream":  true,
+		"options": map[string]interface{}{"num_predict": paramInt(params, "probe_max_tokens", 16)},
+	})
+
+	client := &http.Client{Timeout: paramMillis(params, "probe_timeout_ms", 60*time.Second)}
+	start := time.Now()
+	resp, err := client.Post(baseURL+"/api/generate", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return result
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		result["ollama_probe_status_code"] = int64(resp.StatusCode)
+		return result
+	}
+
+	// One JSON object per line; the last one ("done": true) carries the counters
+	var firstToken time.Time
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var chunk struct {
+			Response           string `json:"response"`
+			Done               bool   `json:"done"`
+			Error              string `json:"error"`
+			EvalCount          int64  `json:"eval_count"`
+			EvalDuration       int64  `json:"eval_duration"` // nanoseconds
+			PromptEvalCount    int64  `json:"prompt_eval_count"`
+			PromptEvalDuration int64  `json:"prompt_eval_duration"`
+			LoadDuration       int64  `json:"load_duration"`
+		}
+		if err := dec.Decode(&chunk); err != nil || chunk.Error != "" {
+			return result
+		}
+		if firstToken.IsZero() && chunk.Response != "" {
+			firstToken = time.Now()
+			result["ollama_probe_ttft_ms"] = firstToken.Sub(start).Milliseconds()
+		}
+		if !chunk.Done {
+			continue
+		}
+
+		result["ollama_probe_up"] = int64(1)
+		result["ollama_probe_latency_ms"] = time.Since(start).Milliseconds()
+		result["ollama_probe_tokens"] = chunk.EvalCount
+		result["ollama_probe_prompt_tokens"] = chunk.PromptEvalCount
+		result["ollama_probe_load_ms"] = chunk.LoadDuration / int64(time.Millisecond)
+		if chunk.EvalDuration > 0 {
+			result["ollama_probe_tokens_per_sec"] = float64(chunk.EvalCount) / (float64(chunk.EvalDuration) / 1e9)
+		}
+		if chunk.PromptEvalDuration > 0 {
+			result["ollama_probe_prompt_tokens_per_sec"] = float64(chunk.PromptEvalCount) / (float64(chunk.PromptEvalDuration) / 1e9)
+		}
+		return result
+	}
 }
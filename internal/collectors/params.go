@@ -0,0 +1,100 @@
+package collectors
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Shared helpers for reading the string-only --param map.
+
+// InstanceParam is set by the daemon to the instance name, so sources that
+// keep state between ticks (keyed by paramsHash) don't mix up two instances
+// configured with the same params.
+const InstanceParam = "_instance"
+
+// splitList splits a comma separated param into trimmed, non-empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// paramBool treats "true", "1", "yes" and "on" as enabled.
+func paramBool(params map[string]string, key string) bool {
+	switch strings.ToLower(strings.TrimSpace(params[key])) {
+	case "true", "1", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// paramInt returns the param as an int, or def if missing/invalid.
+func paramInt(params map[string]string, key string, def int) int {
+	if n, err := strconv.Atoi(strings.TrimSpace(params[key])); err == nil {
+		return n
+	}
+	return def
+}
+
+// paramMillis reads a millisecond param (e.g. timeout_ms) as a Duration.
+func paramMillis(params map[string]string, key string, def time.Duration) time.Duration {
+	if ms := paramInt(params, key, 0); ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return def
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// fanOut emits per-entity metrics (containers, units, ...) either as one ship
+// per entity (default, ship_id = ship_prefix + entity) or, when params[modeKey]
+// is "cargo", as labeled cargo ("metric.entity") merged into the summary ship.
+func fanOut(params map[string]string, modeKey string, summary map[string]interface{}, entities map[string]map[string]interface{}) []map[string]interface{} {
+	results := []map[string]interface{}{summary}
+
+	if params[modeKey] == "cargo" {
+		for name, m := range entities {
+			for k, v := range m {
+				summary[k+"."+name] = v
+			}
+		}
+		return results
+	}
+
+	for name, m := range entities {
+		m["ship_id"] = params["ship_prefix"] + name
+		results = append(results, m)
+	}
+	return results
+}
+
+// labeledCargoID appends label values in label-name order: name.value1.value2
+func labeledCargoID(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range keys {
+		b.WriteByte('.')
+		b.WriteString(labels[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,131 @@
+package collectors
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Schema-less protobuf decoding for devices whose .proto files we don't
+// vendor (Starlink dish). We only ever read a handful of known field numbers.
+
+type pbField struct {
+	typ   protowire.Type
+	num   uint64 // varint, fixed32 and fixed64 values
+	bytes []byte // length-delimited values
+}
+
+// pbMessage maps field numbers to their values in wire order.
+type pbMessage map[protowire.Number][]pbField
+
+func parsePB(b []byte) (pbMessage, error) {
+	m := make(pbMessage)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, fmt.Errorf("bad protobuf tag: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		f := pbField{typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.num, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			f.num = uint64(v)
+		case protowire.Fixed64Type:
+			f.num, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("bad protobuf field %d: %w", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+		m[num] = append(m[num], f)
+	}
+	return m, nil
+}
+
+// last returns the final occurrence of a field (proto3 "last one wins").
+func (m pbMessage) last(n protowire.Number) (pbField, bool) {
+	fs := m[n]
+	if len(fs) == 0 {
+		return pbField{}, false
+	}
+	return fs[len(fs)-1], true
+}
+
+func (m pbMessage) has(n protowire.Number) bool {
+	return len(m[n]) > 0
+}
+
+func (m pbMessage) uint(n protowire.Number) uint64 {
+	f, _ := m.last(n)
+	return f.num
+}
+
+func (m pbMessage) int(n protowire.Number) int64 {
+	return int64(m.uint(n))
+}
+
+func (m pbMessage) bool(n protowire.Number) bool {
+	return m.uint(n) != 0
+}
+
+// float32 reads a proto "float" (fixed32).
+func (m pbMessage) float32(n protowire.Number) float64 {
+	f, _ := m.last(n)
+	return float64(math.Float32frombits(uint32(f.num)))
+}
+
+func (m pbMessage) string(n protowire.Number) string {
+	f, _ := m.last(n)
+	return string(f.bytes)
+}
+
+// message decodes an embedded message; a missing or broken one is empty.
+func (m pbMessage) message(n protowire.Number) pbMessage {
+	f, ok := m.last(n)
+	if !ok {
+		return pbMessage{}
+	}
+	sub, err := parsePB(f.bytes)
+	if err != nil {
+		return pbMessage{}
+	}
+	return sub
+}
+
+func (m pbMessage) messages(n protowire.Number) []pbMessage {
+	var out []pbMessage
+	for _, f := range m[n] {
+		if sub, err := parsePB(f.bytes); err == nil {
+			out = append(out, sub)
+		}
+	}
+	return out
+}
+
+// float32s reads a repeated float, packed or not.
+func (m pbMessage) float32s(n protowire.Number) []float64 {
+	var out []float64
+	for _, f := range m[n] {
+		if f.typ == protowire.Fixed32Type {
+			out = append(out, float64(math.Float32frombits(uint32(f.num))))
+			continue
+		}
+		b := f.bytes
+		for len(b) >= 4 {
+			v, _ := protowire.ConsumeFixed32(b)
+			out = append(out, float64(math.Float32frombits(v)))
+			b = b[4:]
+		}
+	}
+	return out
+}
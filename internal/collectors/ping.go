@@ -0,0 +1,164 @@
+package collectors
+
+import (
+	"math"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// ICMP echo IDs must be unique per in-flight probe when using raw sockets
+var pingSeq atomic.Uint32
+
+// PingCollector sends N ICMP echo requests to each target and reports
+// loss, min/avg/max RTT and jitter. It uses unprivileged datagram sockets
+// where the OS allows it (Linux with net.ipv4.ping_group_range, macOS) and
+// falls back to raw sockets (root / Administrator).
+func PingCollector(params map[string]string) ([]map[string]interface{}, error) {
+	count := max(paramInt(params, "count", 5), 1)
+	gap := paramMillis(params, "interval_ms", 200*time.Millisecond)
+	timeout := paramMillis(params, "timeout_ms", time.Second)
+	size := min(max(paramInt(params, "size", 56), 0), 65000)
+
+	return probeTargets(params, "ping", "ping_up", func(target string) map[string]interface{} {
+		return pingTarget(target, count, gap, timeout, size)
+	})
+}
+
+func pingTarget(target string, count int, gap, timeout time.Duration, size int) map[string]interface{} {
+	result := map[string]interface{}{
+		"ping_up":       int64(0),
+		"ping_sent":     int64(0),
+		"ping_received": int64(0),
+		"ping_loss_pct": float64(100),
+	}
+
+	ip, err := net.ResolveIPAddr("ip", target)
+	if err != nil {
+		result["ping_resolve_error"] = int64(1)
+		return result
+	}
+
+	conn, privileged, err := listenICMP(ip.IP.To4() == nil)
+	if err != nil {
+		result["ping_socket_error"] = int64(1)
+		return result
+	}
+	defer conn.Close()
+
+	var dst net.Addr = ip
+	if !privileged {
+		dst = &net.UDPAddr{IP: ip.IP, Zone: ip.Zone}
+	}
+
+	var echoType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	proto := 1 // ICMPv4
+	if ip.IP.To4() == nil {
+		echoType, replyType, proto = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply, 58
+	}
+
+	id := int(pingSeq.Add(1)+uint32(os.Getpid())) & 0xffff
+	payload := make([]byte, size)
+	var rtts []float64
+	buf := make([]byte, 1500+size)
+
+	for seq := 0; seq < count; seq++ {
+		if seq > 0 {
+			time.Sleep(gap)
+		}
+
+		msg := icmp.Message{Type: echoType, Body: &icmp.Echo{ID: id, Seq: seq, Data: payload}}
+		wire, err := msg.Marshal(nil)
+		if err != nil {
+			break
+		}
+
+		start := time.Now()
+		if _, err := conn.WriteTo(wire, dst); err != nil {
+			continue
+		}
+		result["ping_sent"] = result["ping_sent"].(int64) + 1
+
+		// Wait for our reply; raw sockets also see other processes' traffic
+		deadline := start.Add(timeout)
+		for {
+			conn.SetReadDeadline(deadline)
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				break
+			}
+			reply, err := icmp.ParseMessage(proto, buf[:n])
+			if err != nil || reply.Type != replyType {
+				continue
+			}
+			echo, ok := reply.Body.(*icmp.Echo)
+			// Datagram sockets rewrite the ID, so only check it on raw sockets
+			if !ok || echo.Seq != seq || (privileged && echo.ID != id) || !samePeer(peer, ip.IP) {
+				continue
+			}
+			rtts = append(rtts, float64(time.Since(start).Microseconds())/1000)
+			break
+		}
+	}
+
+	sent := result["ping_sent"].(int64)
+	received := int64(len(rtts))
+	result["ping_received"] = received
+	if sent > 0 {
+		result["ping_loss_pct"] = float64(sent-received) / float64(sent) * 100
+	}
+	if received == 0 {
+		return result
+	}
+
+	minRTT, maxRTT, sum, jitter := rtts[0], rtts[0], 0.0, 0.0
+	for i, r := range rtts {
+		minRTT = math.Min(minRTT, r)
+		maxRTT = math.Max(maxRTT, r)
+		sum += r
+		if i > 0 {
+			jitter += math.Abs(r - rtts[i-1])
+		}
+	}
+
+	result["ping_up"] = int64(1)
+	result["ping_min_ms"] = minRTT
+	result["ping_avg_ms"] = sum / float64(received)
+	result["ping_max_ms"] = maxRTT
+	// Mean deviation between consecutive replies
+	if received > 1 {
+		result["ping_jitter_ms"] = jitter / float64(received-1)
+	} else {
+		result["ping_jitter_ms"] = float64(0)
+	}
+	return result
+}
+
+// listenICMP prefers an unprivileged datagram socket and falls back to raw.
+func listenICMP(v6 bool) (*icmp.PacketConn, bool, error) {
+	dgram, raw, addr := "udp4", "ip4:icmp", "0.0.0.0"
+	if v6 {
+		dgram, raw, addr = "udp6", "ip6:ipv6-icmp", "::"
+	}
+
+	if conn, err := icmp.ListenPacket(dgram, addr); err == nil {
+		return conn, false, nil
+	}
+	conn, err := icmp.ListenPacket(raw, addr)
+	return conn, true, err
+}
+
+func samePeer(peer net.Addr, ip net.IP) bool {
+	switch p := peer.(type) {
+	case *net.UDPAddr:
+		return p.IP.Equal(ip)
+	case *net.IPAddr:
+		return p.IP.Equal(ip)
+	}
+	return false
+}
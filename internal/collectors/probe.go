@@ -0,0 +1,51 @@
+package collectors
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Shared plumbing for the multi-target network probes (tcp, ping, dns).
+
+// probeTargets runs check against every target concurrently and returns
+// the per-target metrics plus a summary counting targets where upKey == 1.
+// Results go out as one ship per target unless target_mode=cargo.
+func probeTargets(params map[string]string, prefix, upKey string, check func(target string) map[string]interface{}) ([]map[string]interface{}, error) {
+	targets := splitList(params["targets"])
+	if len(targets) == 0 && params["target"] != "" {
+		targets = []string{params["target"]}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("missing 'targets' param")
+	}
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		perTarget = make(map[string]map[string]interface{})
+	)
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t string) {
+			defer wg.Done()
+			m := check(t)
+			mu.Lock()
+			perTarget[t] = m
+			mu.Unlock()
+		}(t)
+	}
+	wg.Wait()
+
+	var up int64
+	for _, m := range perTarget {
+		if v, ok := m[upKey].(int64); ok && v == 1 {
+			up++
+		}
+	}
+
+	summary := map[string]interface{}{
+		prefix + "_targets":    int64(len(perTarget)),
+		prefix + "_targets_up": up,
+	}
+	return fanOut(params, "target_mode", summary, perTarget), nil
+}
@@ -0,0 +1,210 @@
+package collectors
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Quantiles estimated from histogram buckets
+var promHistogramQuantiles = []float64{0.5, 0.9, 0.99}
+
+// promOptions controls how scraped samples are turned into ships and cargo.
+type promOptions struct {
+	include   []string // metric family globs to keep (empty = all)
+	exclude   []string // metric family globs to drop
+	shipLabel string   // label whose value becomes the ship_id ("url_host" = scraped host)
+	buckets   bool     // also send raw histogram buckets
+	tagHost   bool     // append the scraped host to cargo IDs (several urls on one ship)
+}
+
+func newPromOptions(params map[string]string) promOptions {
+	return promOptions{
+		include:   splitList(params["include"]),
+		exclude:   splitList(params["exclude"]),
+		shipLabel: params["ship_label"],
+		buckets:   paramBool(params, "histogram_buckets"),
+	}
+}
+
+func (o promOptions) keep(family string) bool {
+	if len(o.include) > 0 && !globAny(o.include, family) {
+		return false
+	}
+	return !globAny(o.exclude, family)
+}
+
+// PrometheusCollector scrapes one or more /metrics endpoints.
+// Labels become part of the cargo_id ("http_requests_total.200.GET"), or
+// pick one label to split samples into ships with --param ship_label=<label>.
+func PrometheusCollector(params map[string]string) ([]map[string]interface{}, error) {
+	urls := splitList(params["urls"])
+	if len(urls) == 0 && params["url"] != "" {
+		urls = []string{params["url"]}
+	}
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("missing 'url' or 'urls' param")
+	}
+
+	client := &http.Client{Timeout: paramMillis(params, "timeout_ms", 10*time.Second)}
+	opts := newPromOptions(params)
+	// Several targets on one ship share series names (go_goroutines, up, ...);
+	// keep them apart like the prometheus_up.<host> health keys
+	opts.tagHost = len(urls) > 1 && opts.shipLabel == ""
+
+	// "" is the instance's own ship
+	ships := map[string]map[string]interface{}{"": {}}
+	summary := ships[""]
+
+	for _, target := range urls {
+		host := target
+		if u, err := url.Parse(target); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		// Only label the scrape health metrics when there is more than one target
+		suffix := ""
+		if len(urls) > 1 {
+			suffix = "." + host
+		}
+
+		start := time.Now()
+		sc, err := scrapeProm(client, target, params["bearer_token"])
+		summary["prometheus_scrape_ms"+suffix] = time.Since(start).Milliseconds()
+		if err != nil {
+			summary["prometheus_up"+suffix] = int64(0)
+			continue
+		}
+		summary["prometheus_up"+suffix] = int64(1)
+		summary["prometheus_samples"+suffix] = int64(len(sc.Samples))
+
+		opts.convert(sc, host, ships)
+	}
+
+	results := []map[string]interface{}{summary}
+	for id, m := range ships {
+		if id == "" || len(m) == 0 {
+			continue
+		}
+		m["ship_id"] = params["ship_prefix"] + id
+		results = append(results, m)
+	}
+	return results, nil
+}
+
+func scrapeProm(client *http.Client, target, token string) (*promScrape, error) {
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Ask for text; we don't speak the protobuf format
+	req.Header.Set("Accept", "text/plain;version=0.0.4;q=1,application/openmetrics-text;q=0.5,*/*;q=0.1")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("scrape %s: %s", target, resp.Status)
+	}
+	return parsePromText(resp.Body)
+}
+
+// convert maps samples onto ships (keyed by ship label value, "" = default ship).
+func (o promOptions) convert(sc *promScrape, host string, ships map[string]map[string]interface{}) {
+	// histogram buckets grouped by ship + series, for quantile estimation
+	type series struct {
+		ship, family string
+		labels       map[string]string
+		buckets      map[float64]float64
+	}
+	histograms := make(map[string]*series)
+
+	for _, s := range sc.Samples {
+		family, typ := sc.family(s.Name)
+		if !o.keep(family) {
+			continue
+		}
+
+		ship := ""
+		switch {
+		case o.shipLabel == "url_host":
+			ship = host
+		case o.shipLabel != "":
+			ship = s.Labels[o.shipLabel]
+			delete(s.Labels, o.shipLabel)
+		}
+		if ships[ship] == nil {
+			ships[ship] = make(map[string]interface{})
+		}
+		out := ships[ship]
+
+		switch {
+		case typ == "histogram" && strings.HasSuffix(s.Name, "_bucket"):
+			le, err := strconv.ParseFloat(s.Labels["le"], 64)
+			if err != nil {
+				continue
+			}
+			delete(s.Labels, "le")
+			id := ship + "\x00" + o.cargoID(family, s.Labels, host)
+			if histograms[id] == nil {
+				histograms[id] = &series{ship: ship, family: family, labels: s.Labels, buckets: map[float64]float64{}}
+			}
+			histograms[id].buckets[le] = s.Value
+
+			if o.buckets {
+				setFinite(out, o.cargoID(s.Name, s.Labels, host)+".le_"+strconv.FormatFloat(le, 'g', -1, 64), s.Value)
+			}
+
+		case typ == "summary" && s.Labels["quantile"] != "":
+			q, err := strconv.ParseFloat(s.Labels["quantile"], 64)
+			if err != nil {
+				continue
+			}
+			delete(s.Labels, "quantile")
+			setFinite(out, o.cargoID(family+"_"+quantileName(q), s.Labels, host), s.Value)
+
+		case strings.HasSuffix(s.Name, "_created"):
+			// OpenMetrics creation timestamps aren't useful as telemetry
+
+		default:
+			setFinite(out, o.cargoID(s.Name, s.Labels, host), s.Value)
+		}
+	}
+
+	for _, h := range histograms {
+		for _, q := range promHistogramQuantiles {
+			setFinite(ships[h.ship], o.cargoID(h.family+"_"+quantileName(q), h.labels, host), histogramQuantile(q, h.buckets))
+		}
+	}
+}
+
+// cargoID is labeledCargoID plus the scraped host when tagHost is set.
+func (o promOptions) cargoID(name string, labels map[string]string, host string) string {
+	id := labeledCargoID(name, labels)
+	if o.tagHost {
+		id += "." + host
+	}
+	return id
+}
+
+// quantileName turns 0.5 into "p50" and 0.999 into "p99.9".
+func quantileName(q float64) string {
+	return "p" + strconv.FormatFloat(math.Round(q*10000)/100, 'f', -1, 64)
+}
+
+// setFinite skips NaN/Inf, which JSON can't encode.
+func setFinite(m map[string]interface{}, key string, v float64) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return
+	}
+	m[key] = v
+}
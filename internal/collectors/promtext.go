@@ -0,0 +1,191 @@
+package collectors
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Minimal parser for the Prometheus text exposition format (and the
+// OpenMetrics text format, which is close enough for our purposes).
+
+type promSample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+type promScrape struct {
+	Samples []promSample
+	Types   map[string]string // family name -> counter, gauge, histogram, summary, untyped
+}
+
+// family resolves a sample name to its metric family and type,
+// e.g. "http_duration_seconds_bucket" -> "http_duration_seconds", "histogram".
+func (s *promScrape) family(name string) (string, string) {
+	if t, ok := s.Types[name]; ok {
+		return name, t
+	}
+	for _, suffix := range []string{"_bucket", "_sum", "_count", "_total", "_created"} {
+		if base, ok := strings.CutSuffix(name, suffix); ok {
+			if t, ok := s.Types[base]; ok {
+				return base, t
+			}
+		}
+	}
+	return name, "untyped"
+}
+
+func parsePromText(r io.Reader) (*promScrape, error) {
+	out := &promScrape{Types: make(map[string]string)}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			// "# TYPE name type"; HELP, EOF and other comments are ignored
+			fields := strings.Fields(line)
+			if len(fields) >= 4 && fields[1] == "TYPE" {
+				out.Types[fields[2]] = strings.ToLower(fields[3])
+			}
+			continue
+		}
+
+		// Skip lines we can't parse rather than dropping the whole scrape
+		if s, err := parsePromSample(line); err == nil {
+			out.Samples = append(out.Samples, s)
+		}
+	}
+
+	return out, scanner.Err()
+}
+
+// parsePromSample parses `name{label="value",...} value [timestamp] [# exemplar]`.
+func parsePromSample(line string) (promSample, error) {
+	s := promSample{Labels: map[string]string{}}
+
+	i := strings.IndexAny(line, "{ \t")
+	if i < 0 {
+		return s, fmt.Errorf("bad sample line: %q", line)
+	}
+	s.Name = line[:i]
+	rest := line[i:]
+
+	if rest[0] == '{' {
+		end, err := parsePromLabels(rest, s.Labels)
+		if err != nil {
+			return s, fmt.Errorf("%w in line: %q", err, line)
+		}
+		rest = rest[end:]
+	}
+
+	// Drop OpenMetrics exemplars
+	if j := strings.Index(rest, " # "); j >= 0 {
+		rest = rest[:j]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return s, fmt.Errorf("missing value: %q", line)
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return s, fmt.Errorf("bad value in line %q: %w", line, err)
+	}
+	s.Value = v
+	return s, nil
+}
+
+// parsePromLabels reads a {...} block into labels and returns the index just past '}'.
+func parsePromLabels(s string, labels map[string]string) (int, error) {
+	i := 1 // skip '{'
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i >= len(s) {
+			return 0, fmt.Errorf("unterminated labels")
+		}
+		if s[i] == '}' {
+			return i + 1, nil
+		}
+
+		eq := strings.IndexByte(s[i:], '=')
+		if eq < 0 {
+			return 0, fmt.Errorf("bad label")
+		}
+		key := strings.TrimSpace(s[i : i+eq])
+		i += eq + 1
+		if i >= len(s) || s[i] != '"' {
+			return 0, fmt.Errorf("unquoted label value")
+		}
+		i++
+
+		var val strings.Builder
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					val.WriteByte('\n')
+				default:
+					val.WriteByte(s[i])
+				}
+				continue
+			}
+			val.WriteByte(s[i])
+		}
+		if i >= len(s) {
+			return 0, fmt.Errorf("unterminated label value")
+		}
+		i++ // closing quote
+		labels[key] = val.String()
+	}
+}
+
+// histogramQuantile estimates a quantile from cumulative buckets (le -> count)
+// using linear interpolation, like PromQL's histogram_quantile.
+func histogramQuantile(q float64, buckets map[float64]float64) float64 {
+	if len(buckets) == 0 {
+		return math.NaN()
+	}
+
+	bounds := make([]float64, 0, len(buckets))
+	for le := range buckets {
+		bounds = append(bounds, le)
+	}
+	sort.Float64s(bounds)
+
+	total := buckets[bounds[len(bounds)-1]]
+	if total == 0 {
+		return math.NaN()
+	}
+	rank := q * total
+
+	prevBound, prevCount := 0.0, 0.0
+	for _, le := range bounds {
+		count := buckets[le]
+		if count >= rank {
+			if math.IsInf(le, 1) {
+				// Can't interpolate into +Inf; report the highest finite bound
+				return prevBound
+			}
+			if count == prevCount {
+				return le
+			}
+			return prevBound + (le-prevBound)*(rank-prevCount)/(count-prevCount)
+		}
+		prevBound, prevCount = le, count
+	}
+	return prevBound
+}
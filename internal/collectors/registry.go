@@ -14,13 +14,65 @@ func Get(name string) (Collector, error) {
 		return ExecCollector, nil
 	case "uptime":
 		return UptimeCollector, nil
+	case "tcp", "port":
+		return TCPCollector, nil
+	case "ping", "icmp":
+		return PingCollector, nil
+	case "dns":
+		return DNSCollector, nil
+	case "tlscert", "ssl", "certificate":
+		return TLSCertCollector, nil
 	case "docker":
 		return DockerCollector, nil
-	case "ollama", "llm", "ai":
-    return OllamaCollector, nil
-  case "starlink", "dishy":
-    return StarlinkCollector, nil
+	case "docker_events", "docker-events":
+		return DockerEventsCollector, nil
+	case "systemd", "services":
+		return SystemdCollector, nil
+	case "logtail", "log":
+		return LogTailCollector, nil
+	case "prometheus", "openmetrics":
+		return PrometheusCollector, nil
+	case "statsd", "dogstatsd":
+		return StatsdCollector, nil
+	case "listen", "webhook", "http_push":
+		return ListenCollector, nil
+	case "syslog":
+		return SyslogCollector, nil
+	case "ollama":
+		return OllamaCollector, nil
+	case "llm", "ai":
+		return LLMCollector, nil
+	case "openai", "vllm", "llamacpp", "llama.cpp":
+		return OpenAICollector, nil
+	case "starlink", "dishy":
+		return StarlinkCollector, nil
+	case "gps", "gnss", "nmea":
+		return GPSCollector, nil
+	case "meshtastic", "mesh":
+		return MeshtasticCollector, nil
+	case "ttn", "thethingsnetwork", "lorawan":
+		return TTNCollector, nil
+	case "mqtt":
+		return MQTTCollector, nil
+	case "modbus":
+		return ModbusCollector, nil
+	case "snmp":
+		return SNMPCollector, nil
+	case "sensors", "hwmon":
+		return SensorsCollector, nil
+	case "nut", "ups":
+		return NUTCollector, nil
 	default:
 		return nil, fmt.Errorf("unknown source: %s", name)
 	}
 }
+
+// Start prepares sources that count events between ticks, so whatever
+// happens before an instance's first tick isn't lost. Other sources start on
+// their first collection.
+func Start(name string, params map[string]string) {
+	switch name {
+	case "docker_events", "docker-events":
+		startDockerEvents(params)
+	}
+}
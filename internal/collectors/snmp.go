@@ -0,0 +1,431 @@
+package collectors
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// snmpCounterState holds the counters an instance read on its last poll,
+// for rates. Each poll replaces it, so rows and OIDs that vanish drop out.
+type snmpCounterState struct {
+	values map[string]uint64
+	at     time.Time
+}
+
+// Instances not polled for this long (params edited, source removed) are forgotten
+const snmpCounterTTL = 24 * time.Hour
+
+var (
+	snmpCountersMu sync.Mutex
+	snmpCounters   = make(map[string]*snmpCounterState) // keyed by instance params
+)
+
+// SNMPCollector polls a device over SNMP v1/v2c/v3.
+//
+//	--param target=192.168.1.1                             (port 161 unless given)
+//	--param oid.uptime=1.3.6.1.2.1.1.3.0                   (GET, reported as "uptime")
+//	--param walk.cpu=1.3.6.1.2.1.25.3.3.1.2                (WALK, reported as "cpu.<index>")
+//	--param table.if=1.3.6.1.2.1.2.2.1                     (table entry OID)
+//	--param columns.if=in_octets:10,out_octets:16,status:8 (metric:column)
+//	--param label.if=2                                     (column naming each row; default: index)
+//
+// Each table row becomes a ship (ship_prefix + label), or labeled cargo
+// ("in_octets.eth0") with row_mode=cargo. Counter32/Counter64 values are
+// reported as <name>_per_sec since the previous poll (counters=raw or both
+// to also send the raw value). TimeTicks are converted to seconds.
+func SNMPCollector(params map[string]string) ([]map[string]interface{}, error) {
+	g, err := newSNMPClient(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.Connect(); err != nil {
+		return nil, fmt.Errorf("connect %s: %w", g.Target, err)
+	}
+	defer g.Conn.Close()
+
+	s := &snmpPoll{
+		params:   params,
+		key:      paramsHash(params),
+		counters: params["counters"],
+		now:      time.Now(),
+		next:     make(map[string]uint64),
+	}
+	if s.counters == "" {
+		s.counters = "rate"
+	}
+	snmpCountersMu.Lock()
+	s.prev = snmpCounters[s.key]
+	snmpCountersMu.Unlock()
+
+	summary := make(map[string]interface{})
+	perRow := make(map[string]map[string]interface{})
+	start := time.Now()
+
+	// GETs, in chunks the agent accepts
+	var names, oids []string
+	for _, name := range sortedParamKeys(params, "oid.") {
+		names = append(names, name)
+		oids = append(oids, strings.TrimPrefix(params["oid."+name], "."))
+	}
+	for i := 0; i < len(oids); i += g.MaxOids {
+		end := min(i+g.MaxOids, len(oids))
+		pkt, err := g.Get(oids[i:end])
+		if err != nil {
+			return nil, fmt.Errorf("get from %s: %w", g.Target, err)
+		}
+		if pkt.Error != gosnmp.NoError {
+			return nil, fmt.Errorf("get from %s: %s", g.Target, pkt.Error)
+		}
+		for j, pdu := range pkt.Variables {
+			if i+j < len(names) {
+				s.store(summary, names[i+j], pdu)
+			}
+		}
+	}
+
+	// WALKs: one value per index below the root
+	for _, name := range sortedParamKeys(params, "walk.") {
+		root := strings.TrimPrefix(params["walk."+name], ".")
+		pdus, err := snmpWalk(g, root)
+		if err != nil {
+			return nil, fmt.Errorf("walk %s on %s: %w", root, g.Target, err)
+		}
+		for _, pdu := range pdus {
+			s.store(summary, name+"."+snmpIndex(root, pdu.Name), pdu)
+		}
+	}
+
+	// Tables: one row per index, named by the label column
+	for _, table := range sortedParamKeys(params, "table.") {
+		rows, err := s.table(g, table)
+		if err != nil {
+			return nil, err
+		}
+		for name, m := range rows {
+			if perRow[name] == nil {
+				perRow[name] = make(map[string]interface{})
+			}
+			for k, v := range m {
+				perRow[name][k] = v // tables sharing an index (ifTable + ifXTable) merge
+			}
+		}
+	}
+
+	s.saveCounters()
+
+	summary["snmp_up"] = int64(1)
+	summary["snmp_latency_ms"] = float64(time.Since(start).Microseconds()) / 1000
+	return fanOut(params, "row_mode", summary, perRow), nil
+}
+
+// snmpPoll carries per-collection state for converting values.
+type snmpPoll struct {
+	params   map[string]string
+	key      string
+	counters string // rate, raw or both
+	now      time.Time
+
+	prev *snmpCounterState // last poll, nil on the first
+	next map[string]uint64 // counters read by this poll
+}
+
+// table walks each configured column of a table and groups values by row.
+func (s *snmpPoll) table(g *gosnmp.GoSNMP, table string) (map[string]map[string]interface{}, error) {
+	entry := strings.TrimPrefix(s.params["table."+table], ".")
+	cols := splitList(s.params["columns."+table])
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("table %s: missing 'columns.%s' param", table, table)
+	}
+
+	// Row names from the label column (e.g. ifDescr), else the index
+	labels := make(map[string]string)
+	if col := s.params["label."+table]; col != "" {
+		root := entry + "." + col
+		pdus, err := snmpWalk(g, root)
+		if err != nil {
+			return nil, fmt.Errorf("walk %s on %s: %w", root, g.Target, err)
+		}
+		for _, pdu := range pdus {
+			if v := snmpString(pdu); v != "" {
+				labels[snmpIndex(root, pdu.Name)] = v
+			}
+		}
+	}
+	filter := splitList(s.params["rows."+table])
+
+	rows := make(map[string]map[string]interface{})
+	for _, c := range cols {
+		metric, col, ok := strings.Cut(c, ":")
+		if !ok {
+			return nil, fmt.Errorf("table %s: column %q must be metric:column", table, c)
+		}
+		root := entry + "." + col
+		pdus, err := snmpWalk(g, root)
+		if err != nil {
+			return nil, fmt.Errorf("walk %s on %s: %w", root, g.Target, err)
+		}
+		for _, pdu := range pdus {
+			index := snmpIndex(root, pdu.Name)
+			name := index
+			if l, ok := labels[index]; ok {
+				name = l
+			}
+			if len(filter) > 0 && !globAny(filter, name) {
+				continue
+			}
+			if rows[name] == nil {
+				rows[name] = make(map[string]interface{})
+			}
+			// Rate state is per index so renamed interfaces don't produce spikes
+			s.storeAs(rows[name], metric, metric, table+"."+index+"."+metric, pdu)
+		}
+	}
+	return rows, nil
+}
+
+// store converts pdu and stores it in out under name.
+func (s *snmpPoll) store(out map[string]interface{}, name string, pdu gosnmp.SnmpPDU) {
+	scaleKey, _, _ := strings.Cut(name, ".") // walk.cpu -> scale.cpu applies to cpu.<index>
+	s.storeAs(out, name, scaleKey, name, pdu)
+}
+
+func (s *snmpPoll) storeAs(out map[string]interface{}, name, scaleKey, stateKey string, pdu gosnmp.SnmpPDU) {
+	scale := 1.0
+	if v, err := strconv.ParseFloat(s.params["scale."+scaleKey], 64); err == nil {
+		scale = v
+	}
+	number := func(v float64) {
+		if scale != 1 {
+			setFinite(out, name, v*scale)
+		} else if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			out[name] = int64(v)
+		} else {
+			setFinite(out, name, v)
+		}
+	}
+
+	switch pdu.Type {
+	case gosnmp.Counter32, gosnmp.Counter64:
+		v := gosnmp.ToBigInt(pdu.Value).Uint64()
+		if s.counters == "raw" || s.counters == "both" {
+			number(float64(v))
+		}
+		if s.counters != "raw" {
+			if rate, ok := s.rate(stateKey, v, pdu.Type == gosnmp.Counter32); ok {
+				setFinite(out, name+"_per_sec", rate*scale)
+			}
+		}
+	case gosnmp.Integer, gosnmp.Gauge32, gosnmp.Uinteger32:
+		number(float64(gosnmp.ToBigInt(pdu.Value).Int64()))
+	case gosnmp.TimeTicks:
+		setFinite(out, name, float64(gosnmp.ToBigInt(pdu.Value).Uint64())/100*scale)
+	case gosnmp.OpaqueFloat:
+		if v, ok := pdu.Value.(float32); ok {
+			setFinite(out, name, float64(v)*scale)
+		}
+	case gosnmp.OpaqueDouble:
+		if v, ok := pdu.Value.(float64); ok {
+			setFinite(out, name, v*scale)
+		}
+	case gosnmp.OctetString, gosnmp.IPAddress, gosnmp.ObjectIdentifier:
+		str := snmpString(pdu)
+		// Many agents (UPS, lm-sensors) report numbers as strings
+		if f, err := strconv.ParseFloat(str, 64); err == nil {
+			number(f)
+		} else if str != "" {
+			out[name] = str
+		}
+	}
+}
+
+// rate returns the per-second increase of a counter since the last poll.
+// A smaller value means a wrap (Counter32) or a device restart (skipped).
+func (s *snmpPoll) rate(stateKey string, v uint64, is32 bool) (float64, bool) {
+	s.next[stateKey] = v
+	if s.prev == nil {
+		return 0, false
+	}
+	prev, ok := s.prev.values[stateKey]
+	if !ok {
+		return 0, false
+	}
+
+	secs := s.now.Sub(s.prev.at).Seconds()
+	if secs <= 0 {
+		return 0, false
+	}
+	delta := v - prev
+	if v < prev {
+		if !is32 {
+			return 0, false
+		}
+		delta = v + (1 << 32) - prev
+	}
+	return float64(delta) / secs, true
+}
+
+// saveCounters keeps this poll's counters for the next one, and forgets
+// instances that stopped polling.
+func (s *snmpPoll) saveCounters() {
+	snmpCountersMu.Lock()
+	defer snmpCountersMu.Unlock()
+
+	for k, st := range snmpCounters {
+		if s.now.Sub(st.at) > snmpCounterTTL {
+			delete(snmpCounters, k)
+		}
+	}
+	if len(s.next) == 0 {
+		delete(snmpCounters, s.key)
+		return
+	}
+	snmpCounters[s.key] = &snmpCounterState{values: s.next, at: s.now}
+}
+
+func newSNMPClient(params map[string]string) (*gosnmp.GoSNMP, error) {
+	target := params["target"]
+	if target == "" {
+		return nil, fmt.Errorf("missing 'target' param")
+	}
+	port := uint16(161)
+	if host, p, err := net.SplitHostPort(target); err == nil {
+		n, err := strconv.ParseUint(p, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port in %q", target)
+		}
+		target, port = host, uint16(n)
+	}
+
+	g := &gosnmp.GoSNMP{
+		Target:         target,
+		Port:           port,
+		Transport:      "udp",
+		Community:      params["community"],
+		Version:        gosnmp.Version2c,
+		Timeout:        paramMillis(params, "timeout_ms", 2*time.Second),
+		Retries:        paramInt(params, "retries", 1),
+		MaxOids:        gosnmp.MaxOids,
+		MaxRepetitions: uint32(paramInt(params, "max_repetitions", 25)),
+	}
+	if g.Community == "" {
+		g.Community = "public"
+	}
+
+	switch params["version"] {
+	case "", "2c", "2":
+	case "1":
+		g.Version = gosnmp.Version1
+	case "3":
+		usm, flags, err := snmpV3Security(params)
+		if err != nil {
+			return nil, err
+		}
+		g.Version = gosnmp.Version3
+		g.SecurityModel = gosnmp.UserSecurityModel
+		g.MsgFlags = flags
+		g.SecurityParameters = usm
+		g.ContextName = params["context"]
+	default:
+		return nil, fmt.Errorf("unsupported SNMP version %q (1, 2c, 3)", params["version"])
+	}
+	return g, nil
+}
+
+// snmpV3Security builds USM parameters. The security level follows from
+// which passwords are set.
+func snmpV3Security(params map[string]string) (*gosnmp.UsmSecurityParameters, gosnmp.SnmpV3MsgFlags, error) {
+	usm := &gosnmp.UsmSecurityParameters{
+		UserName:                 params["username"],
+		AuthenticationProtocol:   gosnmp.NoAuth,
+		PrivacyProtocol:          gosnmp.NoPriv,
+		AuthenticationPassphrase: params["auth_password"],
+		PrivacyPassphrase:        params["priv_password"],
+	}
+	if usm.UserName == "" {
+		return nil, 0, fmt.Errorf("missing 'username' param for SNMPv3")
+	}
+	if usm.AuthenticationPassphrase == "" {
+		return usm, gosnmp.NoAuthNoPriv, nil
+	}
+
+	switch strings.ToUpper(params["auth_protocol"]) {
+	case "", "SHA", "SHA1":
+		usm.AuthenticationProtocol = gosnmp.SHA
+	case "MD5":
+		usm.AuthenticationProtocol = gosnmp.MD5
+	case "SHA224":
+		usm.AuthenticationProtocol = gosnmp.SHA224
+	case "SHA256":
+		usm.AuthenticationProtocol = gosnmp.SHA256
+	case "SHA384":
+		usm.AuthenticationProtocol = gosnmp.SHA384
+	case "SHA512":
+		usm.AuthenticationProtocol = gosnmp.SHA512
+	default:
+		return nil, 0, fmt.Errorf("unsupported auth_protocol %q", params["auth_protocol"])
+	}
+	if usm.PrivacyPassphrase == "" {
+		return usm, gosnmp.AuthNoPriv, nil
+	}
+
+	switch strings.ToUpper(params["priv_protocol"]) {
+	case "", "AES", "AES128":
+		usm.PrivacyProtocol = gosnmp.AES
+	case "DES":
+		usm.PrivacyProtocol = gosnmp.DES
+	case "AES192":
+		usm.PrivacyProtocol = gosnmp.AES192
+	case "AES256":
+		usm.PrivacyProtocol = gosnmp.AES256
+	case "AES192C":
+		usm.PrivacyProtocol = gosnmp.AES192C
+	case "AES256C":
+		usm.PrivacyProtocol = gosnmp.AES256C
+	default:
+		return nil, 0, fmt.Errorf("unsupported priv_protocol %q", params["priv_protocol"])
+	}
+	return usm, gosnmp.AuthPriv, nil
+}
+
+// snmpWalk uses GETBULK where the version allows it.
+func snmpWalk(g *gosnmp.GoSNMP, root string) ([]gosnmp.SnmpPDU, error) {
+	if g.Version == gosnmp.Version1 {
+		return g.WalkAll(root)
+	}
+	return g.BulkWalkAll(root)
+}
+
+// snmpIndex returns the part of oid below root ("1.3.6.1.2.1.2.2.1.10.3" -> "3").
+func snmpIndex(root, oid string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(oid, "."), root+".")
+}
+
+func snmpString(pdu gosnmp.SnmpPDU) string {
+	switch v := pdu.Value.(type) {
+	case []byte:
+		return strings.TrimSpace(strings.TrimRight(string(v), "\x00"))
+	case string:
+		return strings.TrimPrefix(v, ".")
+	}
+	return ""
+}
+
+// sortedParamKeys returns the suffixes of all params starting with prefix.
+func sortedParamKeys(params map[string]string, prefix string) []string {
+	var keys []string
+	for k := range params {
+		if name, ok := strings.CutPrefix(k, prefix); ok && name != "" {
+			keys = append(keys, name)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,314 @@
+package collectors
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// fakeSNMPAgent answers v1/v2c GET, GETNEXT and GETBULK from a fixed MIB.
+type fakeSNMPAgent struct {
+	mu  sync.Mutex
+	mib map[string]gosnmp.SnmpPDU // keyed by OID without the leading dot
+}
+
+func (a *fakeSNMPAgent) set(oid string, typ gosnmp.Asn1BER, value interface{}) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.mib == nil {
+		a.mib = make(map[string]gosnmp.SnmpPDU)
+	}
+	a.mib[oid] = gosnmp.SnmpPDU{Name: "." + oid, Type: typ, Value: value}
+}
+
+func (a *fakeSNMPAgent) remove(oid string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.mib, oid)
+}
+
+func startFakeSNMPAgent(t *testing.T, a *fakeSNMPAgent) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	decoder := &gosnmp.GoSNMP{Version: gosnmp.Version2c}
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			req, err := decoder.SnmpDecodePacket(buf[:n])
+			if err != nil {
+				continue
+			}
+			resp := &gosnmp.SnmpPacket{
+				Version:   req.Version,
+				Community: req.Community,
+				PDUType:   gosnmp.GetResponse,
+				RequestID: req.RequestID,
+				Variables: a.answer(req),
+			}
+			if out, err := resp.MarshalMsg(); err == nil {
+				conn.WriteTo(out, from)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func (a *fakeSNMPAgent) answer(req *gosnmp.SnmpPacket) []gosnmp.SnmpPDU {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	oids := make([]string, 0, len(a.mib))
+	for oid := range a.mib {
+		oids = append(oids, oid)
+	}
+	sort.Slice(oids, func(i, j int) bool { return oidLess(oids[i], oids[j]) })
+
+	// next returns the first OID after oid, or EndOfMibView
+	next := func(oid string) gosnmp.SnmpPDU {
+		i := sort.Search(len(oids), func(i int) bool { return oidLess(oid, oids[i]) })
+		if i == len(oids) {
+			return gosnmp.SnmpPDU{Name: "." + oid, Type: gosnmp.EndOfMibView}
+		}
+		return a.mib[oids[i]]
+	}
+
+	var out []gosnmp.SnmpPDU
+	for _, v := range req.Variables {
+		oid := strings.TrimPrefix(v.Name, ".")
+		switch req.PDUType {
+		case gosnmp.GetRequest:
+			if pdu, ok := a.mib[oid]; ok {
+				out = append(out, pdu)
+			} else {
+				out = append(out, gosnmp.SnmpPDU{Name: v.Name, Type: gosnmp.NoSuchObject})
+			}
+		case gosnmp.GetNextRequest:
+			out = append(out, next(oid))
+		case gosnmp.GetBulkRequest:
+			for range max(req.MaxRepetitions, 1) {
+				pdu := next(oid)
+				out = append(out, pdu)
+				if pdu.Type == gosnmp.EndOfMibView {
+					break
+				}
+				oid = strings.TrimPrefix(pdu.Name, ".")
+			}
+		}
+	}
+	return out
+}
+
+// oidLess compares OIDs numerically, arc by arc.
+func oidLess(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, _ := strconv.Atoi(as[i])
+		y, _ := strconv.Atoi(bs[i])
+		if x != y {
+			return x < y
+		}
+	}
+	return len(as) < len(bs)
+}
+
+const (
+	testSysUptime = "1.3.6.1.2.1.1.3.0"
+	testSysName   = "1.3.6.1.2.1.1.5.0"
+	testCPULoad   = "1.3.6.1.2.1.25.3.3.1.2" // hrProcessorLoad
+	testIfEntry   = "1.3.6.1.2.1.2.2.1"
+)
+
+func newTestSNMPAgent() *fakeSNMPAgent {
+	a := &fakeSNMPAgent{}
+	a.set(testSysUptime, gosnmp.TimeTicks, uint32(123456))
+	a.set(testSysName, gosnmp.OctetString, "core-sw")
+	a.set(testCPULoad+".196608", gosnmp.Integer, 12)
+	a.set(testCPULoad+".196609", gosnmp.Integer, 34)
+
+	// ifTable: ifDescr (2), ifOperStatus (8), ifInOctets (10)
+	a.set(testIfEntry+".2.1", gosnmp.OctetString, "lo")
+	a.set(testIfEntry+".2.2", gosnmp.OctetString, "eth0")
+	a.set(testIfEntry+".8.1", gosnmp.Integer, 1)
+	a.set(testIfEntry+".8.2", gosnmp.Integer, 2)
+	a.set(testIfEntry+".10.1", gosnmp.Counter32, uint32(1000))
+	a.set(testIfEntry+".10.2", gosnmp.Counter32, uint32(4294967000))
+	// Something after the table, so walks have to stop at their root
+	a.set("1.3.6.1.2.1.2.2.2.1", gosnmp.Integer, 99)
+	return a
+}
+
+func testSNMPParams(addr string) map[string]string {
+	return map[string]string{
+		"target":      addr,
+		"timeout_ms":  "500",
+		"retries":     "0",
+		"oid.uptime":  testSysUptime,
+		"oid.name":    testSysName,
+		"oid.missing": "1.3.6.1.2.1.1.99.0",
+		"walk.cpu":    testCPULoad,
+		"table.if":    testIfEntry,
+		"columns.if":  "status:8,in_octets:10",
+		"label.if":    "2",
+		"row_mode":    "cargo",
+	}
+}
+
+func TestSNMPCollector(t *testing.T) {
+	for _, version := range []string{"1", "2c"} {
+		t.Run("v"+version, func(t *testing.T) {
+			addr := startFakeSNMPAgent(t, newTestSNMPAgent())
+			params := testSNMPParams(addr)
+			params["version"] = version
+			params["counters"] = "both"
+			t.Cleanup(func() { delete(snmpCounters, paramsHash(params)) })
+
+			res, err := SNMPCollector(params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res) != 1 {
+				t.Fatalf("got %d results, want 1 (row_mode=cargo)", len(res))
+			}
+			m := res[0]
+			assertMetrics(t, m, map[string]interface{}{
+				"snmp_up":        int64(1),
+				"uptime":         1234.56,
+				"name":           "core-sw",
+				"cpu.196608":     int64(12),
+				"cpu.196609":     int64(34),
+				"status.lo":      int64(1),
+				"status.eth0":    int64(2),
+				"in_octets.lo":   int64(1000),
+				"in_octets.eth0": int64(4294967000),
+			})
+			for _, k := range []string{"missing", "in_octets_per_sec.lo", "cpu.1"} {
+				if _, ok := m[k]; ok {
+					t.Errorf("%s should not be set", k)
+				}
+			}
+		})
+	}
+}
+
+func TestSNMPCollectorRows(t *testing.T) {
+	addr := startFakeSNMPAgent(t, newTestSNMPAgent())
+	params := testSNMPParams(addr)
+	delete(params, "row_mode")
+	params["ship_prefix"] = "sw-"
+	t.Cleanup(func() { delete(snmpCounters, paramsHash(params)) })
+
+	res, err := SNMPCollector(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ships := make(map[string]map[string]interface{})
+	for _, r := range res[1:] {
+		ships[r["ship_id"].(string)] = r
+	}
+	if len(ships) != 2 || ships["sw-lo"]["status"] != int64(1) || ships["sw-eth0"]["status"] != int64(2) {
+		t.Errorf("rows = %v, want ships sw-lo and sw-eth0", ships)
+	}
+}
+
+func TestSNMPCounterRates(t *testing.T) {
+	agent := newTestSNMPAgent()
+	agent.set(testIfEntry+".10.3", gosnmp.Counter64, uint64(5000))
+	agent.set(testIfEntry+".2.3", gosnmp.OctetString, "eth1")
+	addr := startFakeSNMPAgent(t, agent)
+	params := testSNMPParams(addr)
+	key := paramsHash(params)
+	t.Cleanup(func() { delete(snmpCounters, key) })
+
+	if _, err := SNMPCollector(params); err != nil {
+		t.Fatal(err)
+	}
+	// Pretend the first poll was 10s ago
+	snmpCounters[key].at = snmpCounters[key].at.Add(-10 * time.Second)
+
+	agent.set(testIfEntry+".10.1", gosnmp.Counter32, uint32(1500))
+	agent.set(testIfEntry+".10.2", gosnmp.Counter32, uint32(200)) // wrapped past 2^32
+	agent.set(testIfEntry+".10.3", gosnmp.Counter64, uint64(10))  // device restarted
+
+	res, err := SNMPCollector(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := res[0]
+
+	approx := func(k string, want float64) {
+		t.Helper()
+		got, ok := m[k].(float64)
+		// the poll itself adds a little to the 10s
+		if !ok || got > want || got < want*0.95 {
+			t.Errorf("%s = %v, want ~%v", k, m[k], want)
+		}
+	}
+	approx("in_octets_per_sec.lo", 50)     // 500 in 10s
+	approx("in_octets_per_sec.eth0", 49.6) // 200 + 2^32 - 4294967000 = 496
+	if _, ok := m["in_octets_per_sec.eth1"]; ok {
+		t.Error("a Counter64 that went backwards should be skipped")
+	}
+	if _, ok := m["in_octets.lo"]; ok {
+		t.Error("raw counter sent without counters=raw|both")
+	}
+
+	// A row that disappears takes its counter state with it
+	agent.remove(testIfEntry + ".10.3")
+	agent.remove(testIfEntry + ".2.3")
+	if _, err := SNMPCollector(params); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := snmpCounters[key].values["if.3.in_octets"]; ok {
+		t.Error("counter state kept for a vanished row")
+	}
+	if _, ok := snmpCounters[key].values["if.1.in_octets"]; !ok {
+		t.Error("counter state missing for a present row")
+	}
+}
+
+func TestSNMPCounterStateExpires(t *testing.T) {
+	addr := startFakeSNMPAgent(t, newTestSNMPAgent())
+	params := testSNMPParams(addr)
+	t.Cleanup(func() { delete(snmpCounters, paramsHash(params)) })
+
+	// An instance whose params were edited long ago
+	snmpCounters["stale"] = &snmpCounterState{
+		values: map[string]uint64{"x": 1},
+		at:     time.Now().Add(-snmpCounterTTL - time.Minute),
+	}
+	if _, err := SNMPCollector(params); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := snmpCounters["stale"]; ok {
+		t.Error("stale instance state not pruned")
+	}
+}
+
+func TestSNMPUnreachable(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := conn.LocalAddr().String()
+	conn.Close()
+
+	if _, err := SNMPCollector(map[string]string{"target": addr, "timeout_ms": "200", "retries": "0", "oid.x": testSysUptime}); err == nil {
+		t.Error("expected an error when nothing answers")
+	}
+}
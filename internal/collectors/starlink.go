@@ -7,8 +7,13 @@ import (
 )
 
 // StarlinkCollector gathers telemetry from the local Starlink Dish.
-// Target: http://192.168.100.1/api/get_status_data (Standard Dishy endpoint)
+// Dishes speak gRPC on 192.168.100.1:9200 (--param address=host:port).
+// Setting --param url=... uses a JSON status endpoint instead.
 func StarlinkCollector(params map[string]string) ([]map[string]interface{}, error) {
+	if params["url"] == "" {
+		return starlinkGRPC(params)
+	}
+
 	// 1. Configuration
 	targetURL := "http://192.168.100.1/api/get_status_data"
 	if url, ok := params["url"]; ok && url != "" {
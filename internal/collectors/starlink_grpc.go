@@ -0,0 +1,228 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The dish serves a single gRPC method that takes a oneof Request and
+// returns a oneof Response (package SpaceX.API.Device). Field numbers below
+// come from the dish's reflection descriptors.
+const starlinkHandleMethod = "/SpaceX.API.Device.Device/Handle"
+
+const (
+	dishReqGetStatus   protowire.Number = 1004
+	dishReqGetHistory  protowire.Number = 1007
+	dishRespGetStatus  protowire.Number = 2004
+	dishRespGetHistory protowire.Number = 2006
+)
+
+// DishAlerts field numbers (all bools)
+var starlinkAlerts = []struct {
+	num  protowire.Number
+	name string
+}{
+	{1, "motors_stuck"},
+	{2, "thermal_shutdown"},
+	{3, "thermal_throttle"},
+	{4, "unexpected_location"},
+	{5, "mast_not_near_vertical"},
+	{6, "slow_ethernet_speeds"},
+	{7, "roaming"},
+	{8, "install_pending"},
+	{9, "is_heating"},
+	{10, "power_supply_thermal_throttle"},
+	{11, "is_power_save_idle"},
+}
+
+// One connection per dish address, reused across collections
+var (
+	starlinkConns    = make(map[string]*grpc.ClientConn)
+	starlinkVersions = make(map[string]string) // last logged firmware per address
+	starlinkMu       sync.Mutex
+)
+
+func starlinkConn(addr string) (*grpc.ClientConn, error) {
+	starlinkMu.Lock()
+	defer starlinkMu.Unlock()
+
+	if conn, ok := starlinkConns[addr]; ok {
+		return conn, nil
+	}
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	starlinkConns[addr] = conn
+	return conn, nil
+}
+
+// rawCodec passes pre-encoded protobuf bytes straight through gRPC, so we
+// don't need generated code for the dish API.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("rawCodec: unexpected type %T", v)
+	}
+	return *b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("rawCodec: unexpected type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string { return "proto" }
+
+// dishCall sends Request{<reqField>: {}} and returns the <respField> message.
+func dishCall(ctx context.Context, conn *grpc.ClientConn, reqField, respField protowire.Number) (pbMessage, error) {
+	req := protowire.AppendTag(nil, reqField, protowire.BytesType)
+	req = protowire.AppendBytes(req, nil)
+
+	var resp []byte
+	if err := conn.Invoke(ctx, starlinkHandleMethod, &req, &resp, grpc.ForceCodec(rawCodec{})); err != nil {
+		return nil, err
+	}
+	m, err := parsePB(resp)
+	if err != nil {
+		return nil, err
+	}
+	if !m.has(respField) {
+		return nil, fmt.Errorf("dish response has no field %d", respField)
+	}
+	return m.message(respField), nil
+}
+
+// starlinkGRPC collects status and history from the dish's gRPC API.
+func starlinkGRPC(params map[string]string) ([]map[string]interface{}, error) {
+	addr := params["address"]
+	if addr == "" {
+		addr = "192.168.100.1:9200"
+	}
+	down := []map[string]interface{}{{"starlink_connected": 0}}
+
+	conn, err := starlinkConn(addr)
+	if err != nil {
+		return down, nil
+	}
+
+	// Dishy can be slow to respond during storms
+	ctx, cancel := context.WithTimeout(context.Background(), paramMillis(params, "timeout_ms", 3*time.Second))
+	defer cancel()
+
+	status, err := dishCall(ctx, conn, dishReqGetStatus, dishRespGetStatus)
+	if err != nil {
+		return down, nil
+	}
+
+	result := starlinkStatusMetrics(status)
+	result["starlink_connected"] = 1
+	logStarlinkVersion(addr, status)
+
+	// History is optional; older firmware or a busy dish may refuse it
+	if history, err := dishCall(ctx, conn, dishReqGetHistory, dishRespGetHistory); err == nil {
+		for k, v := range starlinkHistoryMetrics(history) {
+			result[k] = v
+		}
+		for k, v := range starlinkIntervalMetrics(paramsHash(params), history) {
+			result[k] = v
+		}
+	}
+
+	return []map[string]interface{}{result}, nil
+}
+
+// logStarlinkVersion logs the dish firmware and hardware when first seen and
+// after an update. They're strings, so they aren't sent as metrics.
+func logStarlinkVersion(addr string, s pbMessage) {
+	info := s.message(1)
+	version := fmt.Sprintf("software %s, hardware %s", info.string(3), info.string(2))
+
+	starlinkMu.Lock()
+	defer starlinkMu.Unlock()
+	if starlinkVersions[addr] != version {
+		starlinkVersions[addr] = version
+		log.Printf("🛰️ Starlink %s: %s", addr, version)
+	}
+}
+
+// starlinkStatusMetrics maps DishGetStatusResponse onto the same keys the
+// JSON endpoint produced, plus alerts, outage, SNR and GPS.
+func starlinkStatusMetrics(s pbMessage) map[string]interface{} {
+	state := s.message(2)
+	obstruction := s.message(1004)
+	gps := s.message(1015)
+
+	result := map[string]interface{}{
+		"starlink_uptime_seconds":  state.int(1),
+		"starlink_latency_ms":      s.float32(1009),
+		"starlink_packet_loss_pct": s.float32(1003) * 100,     // Convert 0.01 to 1%
+		"starlink_downlink_mbps":   s.float32(1007) / 1000000, // Bits -> Megabits
+		"starlink_uplink_mbps":     s.float32(1008) / 1000000,
+		"starlink_obstruction_pct": obstruction.float32(1) * 100,
+		"starlink_obstructed":      boolToInt(obstruction.bool(5)),
+
+		"starlink_snr_above_noise_floor": boolToInt(s.bool(1018)),
+		"starlink_snr_persistently_low":  boolToInt(s.bool(1022)),
+		"starlink_gps_valid":             boolToInt(gps.bool(1)),
+		"starlink_gps_sats":              gps.int(2),
+		"starlink_eth_speed_mbps":        s.int(1016),
+	}
+
+	// Alerts: named flags we know, plus a count of every flag that's set
+	alerts := s.message(1005)
+	var active int64
+	for _, fs := range alerts {
+		for _, f := range fs {
+			if f.typ == protowire.VarintType && f.num != 0 {
+				active++
+			}
+		}
+	}
+	for _, a := range starlinkAlerts {
+		result["starlink_alert_"+a.name] = boolToInt(alerts.bool(a.num))
+	}
+	result["starlink_alerts_active"] = active
+
+	// Current outage (only present while disconnected)
+	result["starlink_outage_active"] = int64(0)
+	if s.has(1006) {
+		outage := s.message(1006)
+		result["starlink_outage_active"] = int64(1)
+		result["starlink_outage_cause"] = outage.int(1)
+		result["starlink_outage_duration_s"] = float64(outage.uint(3)) / 1e9
+	}
+	return result
+}
+
+// starlinkHistoryMetrics reports the most recent outage in the history buffer.
+func starlinkHistoryMetrics(h pbMessage) map[string]interface{} {
+	outages := h.messages(1009)
+	result := map[string]interface{}{
+		"starlink_history_outages": int64(len(outages)),
+	}
+	if len(outages) > 0 {
+		last := outages[0]
+		for _, o := range outages[1:] {
+			if o.uint(2) > last.uint(2) { // start_timestamp_ns
+				last = o
+			}
+		}
+		result["starlink_last_outage_cause"] = last.int(1)
+		result["starlink_last_outage_duration_s"] = float64(last.uint(3)) / 1e9
+	}
+	return result
+}
@@ -0,0 +1,306 @@
+package collectors
+
+import (
+	"math"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers below are written out from SpaceX.API.Device's dish.proto
+// rather than taken from the collector's constants, so a wrong number in
+// the collector fails the test.
+
+// pb is a tiny protobuf encoder for building dish messages by hand.
+type pb []byte
+
+func (b pb) varint(num protowire.Number, v uint64) pb {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func (b pb) boolean(num protowire.Number, v bool) pb {
+	if !v {
+		return b
+	}
+	return b.varint(num, 1)
+}
+
+func (b pb) float(num protowire.Number, v float32) pb {
+	b = protowire.AppendTag(b, num, protowire.Fixed32Type)
+	return protowire.AppendFixed32(b, math.Float32bits(v))
+}
+
+func (b pb) str(num protowire.Number, v string) pb {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func (b pb) msg(num protowire.Number, m pb) pb {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m)
+}
+
+// floats encodes a packed repeated float.
+func (b pb) floats(num protowire.Number, vs []float32) pb {
+	var packed []byte
+	for _, v := range vs {
+		packed = protowire.AppendFixed32(packed, math.Float32bits(v))
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, packed)
+}
+
+// dishOutage builds a DishOutage{cause=1, start_timestamp_ns=2, duration_ns=3}.
+func dishOutage(cause, startNs, durationNs uint64) pb {
+	return pb{}.varint(1, cause).varint(2, startNs).varint(3, durationNs)
+}
+
+func testDishStatus() pb {
+	return pb{}.
+		msg(1, pb{}.str(1, "ut01000000-00000000-00000000").str(2, "rev3_proto2").str(3, "2024.05.0.mr12345")). // device_info
+		msg(2, pb{}.varint(1, 86400)).                                                                         // device_state.uptime_s
+		float(1003, 0.02).                                                                                     // pop_ping_drop_rate
+		msg(1004, pb{}.float(1, 0.05).boolean(5, true)).                                                       // obstruction_stats: fraction_obstructed, currently_obstructed
+		msg(1005, pb{}.boolean(1, true).boolean(3, true).boolean(9, true)).                                    // alerts: motors_stuck, thermal_throttle, is_heating
+		msg(1006, dishOutage(6, 1_700_000_000_000_000_000, 4_500_000_000)).                                    // outage (obstructed, 4.5s)
+		float(1007, 150_000_000).                                                                              // downlink_throughput_bps
+		float(1008, 12_000_000).                                                                               // uplink_throughput_bps
+		float(1009, 38.5).                                                                                     // pop_ping_latency_ms
+		msg(1015, pb{}.boolean(1, true).varint(2, 14)).                                                        // gps_stats: gps_valid, gps_sats
+		varint(1016, 1000).                                                                                    // eth_speed_mbps
+		boolean(1018, true)                                                                                    // is_snr_above_noise_floor
+}
+
+func testDishHistory() pb {
+	return pb{}.
+		varint(1, 8). // current
+		floats(1001, []float32{0, 0, 0, 0, 0, 0, 0, 1}).
+		floats(1002, []float32{30, 31, 32, 33, 34, 35, 36, 0}).
+		floats(1003, []float32{1e6, 2e6, 3e6, 4e6, 5e6, 6e6, 7e6, 0}).
+		floats(1004, []float32{1e5, 1e5, 1e5, 1e5, 1e5, 1e5, 1e5, 0}).
+		msg(1009, dishOutage(8, 100, 2_000_000_000)). // outages: no_pings
+		msg(1009, dishOutage(1, 300, 7_000_000_000))  // booting, the newest
+}
+
+// startFakeDish serves /SpaceX.API.Device.Device/Handle: Request.get_status
+// (1004) gets Response.dish_get_status (2004), Request.get_history (1007)
+// gets Response.dish_get_history (2006) unless history is nil.
+func startFakeDish(t *testing.T, statusMsg, history pb) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(
+		grpc.ForceServerCodec(rawCodec{}),
+		grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+			if m, _ := grpc.MethodFromServerStream(stream); m != starlinkHandleMethod {
+				return status.Errorf(codes.Unimplemented, "unexpected method %s", m)
+			}
+			var req []byte
+			if err := stream.RecvMsg(&req); err != nil {
+				return err
+			}
+			r, err := parsePB(req)
+			if err != nil {
+				return err
+			}
+
+			var resp []byte
+			switch {
+			case r.has(1004):
+				resp = pb{}.msg(2004, statusMsg)
+			case r.has(1007) && history != nil:
+				resp = pb{}.msg(2006, history)
+			default:
+				return status.Error(codes.Unimplemented, "unsupported request")
+			}
+			return stream.SendMsg(&resp)
+		}),
+	)
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+	return ln.Addr().String()
+}
+
+func assertMetrics(t *testing.T, got map[string]interface{}, want map[string]interface{}) {
+	t.Helper()
+	for k, w := range want {
+		g, ok := got[k]
+		if !ok {
+			t.Errorf("%s: missing", k)
+			continue
+		}
+		if wf, ok := w.(float64); ok {
+			gf, ok := g.(float64)
+			if !ok || math.Abs(gf-wf) > 1e-6*math.Max(1, math.Abs(wf)) {
+				t.Errorf("%s = %v (%T), want %v", k, g, g, w)
+			}
+			continue
+		}
+		if g != w {
+			t.Errorf("%s = %v (%T), want %v (%T)", k, g, g, w, w)
+		}
+	}
+}
+
+func TestStarlinkGRPCStatusAndHistory(t *testing.T) {
+	addr := startFakeDish(t, testDishStatus(), testDishHistory())
+
+	res, err := starlinkGRPC(map[string]string{"address": addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 {
+		t.Fatalf("got %d results, want 1", len(res))
+	}
+	m := res[0]
+
+	assertMetrics(t, m, map[string]interface{}{
+		"starlink_connected":             1,
+		"starlink_uptime_seconds":        int64(86400),
+		"starlink_latency_ms":            38.5,
+		"starlink_packet_loss_pct":       float64(float32(0.02)) * 100,
+		"starlink_downlink_mbps":         150.0,
+		"starlink_uplink_mbps":           12.0,
+		"starlink_obstruction_pct":       float64(float32(0.05)) * 100,
+		"starlink_obstructed":            int64(1),
+		"starlink_snr_above_noise_floor": int64(1),
+		"starlink_snr_persistently_low":  int64(0),
+		"starlink_gps_valid":             int64(1),
+		"starlink_gps_sats":              int64(14),
+		"starlink_eth_speed_mbps":        int64(1000),
+
+		"starlink_alert_motors_stuck":     int64(1),
+		"starlink_alert_thermal_shutdown": int64(0),
+		"starlink_alert_thermal_throttle": int64(1),
+		"starlink_alert_is_heating":       int64(1),
+		"starlink_alert_roaming":          int64(0),
+		"starlink_alerts_active":          int64(3),
+
+		"starlink_outage_active":     int64(1),
+		"starlink_outage_cause":      int64(6),
+		"starlink_outage_duration_s": 4.5,
+
+		"starlink_history_outages":        int64(2),
+		"starlink_last_outage_cause":      int64(1),
+		"starlink_last_outage_duration_s": 7.0,
+
+		"starlink_interval_samples":  int64(8),
+		"starlink_interval_outage_s": int64(1),
+		"starlink_interval_outages":  int64(1),
+	})
+	for k, v := range m {
+		if _, ok := v.(string); ok {
+			t.Errorf("%s = %q: only numbers are sent", k, v)
+		}
+	}
+}
+
+func TestStarlinkGRPCNoOutageNoHistory(t *testing.T) {
+	s := pb{}.msg(2, pb{}.varint(1, 10)).float(1009, 25)
+	addr := startFakeDish(t, s, nil)
+
+	res, err := starlinkGRPC(map[string]string{"address": addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := res[0]
+	assertMetrics(t, m, map[string]interface{}{
+		"starlink_connected":     1,
+		"starlink_outage_active": int64(0),
+		"starlink_alerts_active": int64(0),
+		"starlink_latency_ms":    25.0,
+	})
+	for _, k := range []string{"starlink_outage_cause", "starlink_history_outages", "starlink_interval_samples"} {
+		if _, ok := m[k]; ok {
+			t.Errorf("%s should not be set", k)
+		}
+	}
+}
+
+func TestStarlinkGRPCUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	res, err := starlinkGRPC(map[string]string{"address": addr, "timeout_ms": "500"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res[0]["starlink_connected"] != 0 {
+		t.Errorf("starlink_connected = %v, want 0", res[0]["starlink_connected"])
+	}
+}
+
+// The history ring buffer wraps: with 10 slots, samples 8..14 live at
+// indexes 8, 9, 0, 1, 2, 3, 4.
+func TestStarlinkIntervalWraparound(t *testing.T) {
+	const key = "wraparound-test"
+	delete(starlinkHistory, key)
+
+	// First collection: samples 0..7, the last one a full drop
+	first := make([]float32, 10)
+	first[7] = 1
+	lat := make([]float32, 10)
+	h1, err := parsePB(pb{}.
+		varint(1, 8).
+		floats(1001, first).
+		floats(1002, lat).
+		msg(1009, dishOutage(8, 100, 1_000_000_000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := starlinkIntervalMetrics(key, h1)
+	assertMetrics(t, m, map[string]interface{}{
+		"starlink_interval_samples":  int64(8),
+		"starlink_interval_outage_s": int64(1),
+		"starlink_interval_outages":  int64(1),
+	})
+	if _, ok := m["starlink_interval_dish_outages"]; ok {
+		t.Error("dish outages reported before a baseline exists")
+	}
+
+	// Second collection: samples 8..14. Sample 8 continues the outage from
+	// sample 7 (not a new one), samples 10 and 11 are a new outage.
+	drop := make([]float32, 10)
+	latency := make([]float32, 10)
+	down := make([]float32, 10)
+	for sample := 5; sample < 15; sample++ { // what the buffer holds now
+		idx := sample % 10
+		latency[idx] = float32(sample * 10)
+		down[idx] = float32(sample) * 1e6
+	}
+	drop[8%10], drop[10%10], drop[11%10] = 1, 1, 1
+	h2, err := parsePB(pb{}.
+		varint(1, 15).
+		floats(1001, drop).
+		floats(1002, latency).
+		floats(1003, down).
+		msg(1009, dishOutage(8, 100, 1_000_000_000)).
+		msg(1009, dishOutage(6, 200, 2_500_000_000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = starlinkIntervalMetrics(key, h2)
+	assertMetrics(t, m, map[string]interface{}{
+		"starlink_interval_samples":             int64(7),
+		"starlink_interval_outage_s":            int64(3),
+		"starlink_interval_outages":             int64(1),
+		"starlink_interval_latency_max_ms":      140.0,
+		"starlink_interval_latency_avg_ms":      120.0, // samples 9, 12, 13, 14
+		"starlink_interval_downlink_peak_mbps":  14.0,
+		"starlink_interval_dish_outages":        int64(1),
+		"starlink_interval_dish_outage_s":       2.5,
+		"starlink_interval_outage_obstructed_s": 2.5,
+	})
+}
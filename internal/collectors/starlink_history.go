@@ -0,0 +1,182 @@
+package collectors
+
+import (
+	"sort"
+	"sync"
+)
+
+// The dish keeps per-second samples in ring buffers; "current" counts every
+// sample ever written, so sample i lives at index i % len(buffer). We remember
+// where the previous collection stopped and aggregate only the new seconds.
+
+// Samples aggregated on the first collection, before we have a baseline
+const starlinkFirstWindow = 60
+
+type starlinkHistoryState struct {
+	current     uint64 // samples seen so far
+	down        bool   // last sample was a full ping drop (outage continues)
+	outageStart uint64 // newest outage start_timestamp_ns already counted
+	initialized bool
+}
+
+var (
+	starlinkHistory   = make(map[string]*starlinkHistoryState)
+	starlinkHistoryMu sync.Mutex
+)
+
+// starlinkIntervalMetrics aggregates the history samples since the last call
+// for this instance (key).
+func starlinkIntervalMetrics(key string, h pbMessage) map[string]interface{} {
+	current := h.uint(1)
+	drop := h.float32s(1001)
+	latency := h.float32s(1002)
+	down := h.float32s(1003)
+	up := h.float32s(1004)
+
+	size := uint64(len(drop))
+	if size == 0 || len(latency) != len(drop) {
+		return nil
+	}
+
+	starlinkHistoryMu.Lock()
+	defer starlinkHistoryMu.Unlock()
+
+	st := starlinkHistory[key]
+	if st == nil {
+		st = &starlinkHistoryState{}
+		starlinkHistory[key] = st
+	}
+
+	// How many new samples; a reboot resets the counter, and anything older
+	// than the buffer has already been overwritten
+	n := uint64(starlinkFirstWindow)
+	if st.initialized && current >= st.current {
+		n = current - st.current
+	} else if st.initialized {
+		n = current
+	}
+	n = min(n, size, current)
+
+	result := map[string]interface{}{
+		"starlink_interval_samples":  int64(n),
+		"starlink_interval_outage_s": int64(0),
+		"starlink_interval_outages":  int64(0),
+	}
+
+	var (
+		lat, drops             []float64
+		peakDown, peakUp       float64
+		sumDown, sumUp         float64
+		outageSeconds, outages int64
+		wasDown                = st.initialized && st.down
+	)
+	for i := current - n; i < current; i++ {
+		idx := i % size
+		d := drop[idx]
+		drops = append(drops, d*100)
+
+		// A full drop is a second without connectivity; latency is meaningless then
+		if d >= 1 {
+			outageSeconds++
+			if !wasDown {
+				outages++
+			}
+			wasDown = true
+		} else {
+			wasDown = false
+			lat = append(lat, latency[idx])
+		}
+
+		if int(idx) < len(down) {
+			peakDown = max(peakDown, down[idx])
+			sumDown += down[idx]
+		}
+		if int(idx) < len(up) {
+			peakUp = max(peakUp, up[idx])
+			sumUp += up[idx]
+		}
+	}
+
+	if n > 0 {
+		st.down = wasDown
+		result["starlink_interval_outage_s"] = outageSeconds
+		result["starlink_interval_outages"] = outages
+		result["starlink_interval_downlink_peak_mbps"] = peakDown / 1000000
+		result["starlink_interval_uplink_peak_mbps"] = peakUp / 1000000
+		result["starlink_interval_downlink_avg_mbps"] = sumDown / float64(n) / 1000000
+		result["starlink_interval_uplink_avg_mbps"] = sumUp / float64(n) / 1000000
+
+		sort.Float64s(drops)
+		var sumDrop float64
+		for _, d := range drops {
+			sumDrop += d
+		}
+		result["starlink_interval_drop_pct_avg"] = sumDrop / float64(len(drops))
+		for _, p := range []float64{50, 90, 99} {
+			result["starlink_interval_drop_pct_"+quantileName(p/100)] = percentile(drops, p)
+		}
+	}
+
+	if len(lat) > 0 {
+		sort.Float64s(lat)
+		var sumLat float64
+		for _, l := range lat {
+			sumLat += l
+		}
+		result["starlink_interval_latency_avg_ms"] = sumLat / float64(len(lat))
+		result["starlink_interval_latency_max_ms"] = lat[len(lat)-1]
+		for _, p := range []float64{50, 90, 99} {
+			result["starlink_interval_latency_"+quantileName(p/100)+"_ms"] = percentile(lat, p)
+		}
+	}
+
+	// Outages the dish itself logged (with a cause) that started since last time
+	var newest uint64
+	var dishOutages int64
+	var dishOutageSeconds float64
+	for _, o := range h.messages(1009) {
+		start := o.uint(2)
+		newest = max(newest, start)
+		if !st.initialized || start <= st.outageStart {
+			continue
+		}
+		dishOutages++
+		dishOutageSeconds += float64(o.uint(3)) / 1e9
+		cause := starlinkOutageCause(o.int(1))
+		id := "starlink_interval_outage_" + cause + "_s"
+		prev, _ := result[id].(float64)
+		result[id] = prev + float64(o.uint(3))/1e9
+	}
+	if st.initialized {
+		result["starlink_interval_dish_outages"] = dishOutages
+		result["starlink_interval_dish_outage_s"] = dishOutageSeconds
+	}
+	st.outageStart = max(st.outageStart, newest)
+
+	st.current = current
+	st.initialized = true
+	return result
+}
+
+// starlinkOutageCause names DishOutage.Cause values for cargo IDs.
+func starlinkOutageCause(c int64) string {
+	switch c {
+	case 1:
+		return "booting"
+	case 2:
+		return "stowed"
+	case 3:
+		return "thermal_shutdown"
+	case 4:
+		return "no_schedule"
+	case 5:
+		return "no_sats"
+	case 6:
+		return "obstructed"
+	case 7:
+		return "no_downlink"
+	case 8:
+		return "no_pings"
+	}
+	return "unknown"
+}
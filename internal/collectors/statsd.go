@@ -0,0 +1,300 @@
+package collectors
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	statsdDefaultListen = ":8125"
+	// Timer samples kept per metric per interval (a uniform random sample of
+	// all values beyond that); count/min/max/sum stay exact
+	statsdMaxTimerSamples = 10000
+)
+
+type statsdKey struct {
+	ship, id string // ship "" is the instance's own ship
+}
+
+type statsdTimer struct {
+	count         float64 // scaled by sample rate
+	observed      int64   // values actually received
+	sum, min, max float64
+	samples       []float64
+}
+
+// statsdServer owns one UDP socket and aggregates everything received
+// between two collector ticks.
+type statsdServer struct {
+	mu       sync.Mutex
+	counters map[statsdKey]float64
+	gauges   map[statsdKey]float64
+	timers   map[statsdKey]*statsdTimer
+	sets     map[statsdKey]map[string]struct{}
+	packets  int64
+	bad      int64
+	shipTag  string
+	owner    string // instance that opened it; a flush drains everything
+}
+
+var (
+	statsdServersMu sync.Mutex
+	statsdServers   = make(map[string]*statsdServer) // keyed by listen address
+)
+
+// StatsdCollector listens for StatsD/DogStatsD on UDP and flushes the
+// aggregate of each interval. The first call opens the socket.
+func StatsdCollector(params map[string]string) ([]map[string]interface{}, error) {
+	addr := params["listen"]
+	if addr == "" {
+		addr = statsdDefaultListen
+	}
+
+	srv, err := getStatsdServer(addr, params[InstanceParam], params["ship_tag"])
+	if err != nil {
+		return nil, err
+	}
+
+	percentiles := []float64{50, 90, 95, 99}
+	if p := splitList(params["percentiles"]); len(p) > 0 {
+		percentiles = percentiles[:0]
+		for _, s := range p {
+			if v, err := strconv.ParseFloat(s, 64); err == nil && v > 0 && v <= 100 {
+				percentiles = append(percentiles, v)
+			}
+		}
+	}
+
+	return srv.flush(percentiles, paramBool(params, "delete_gauges"), params["ship_prefix"]), nil
+}
+
+func getStatsdServer(addr, owner, shipTag string) (*statsdServer, error) {
+	statsdServersMu.Lock()
+	defer statsdServersMu.Unlock()
+
+	if srv, ok := statsdServers[addr]; ok {
+		// Two instances would each get part of the traffic, and only the
+		// first one's ship_tag would apply
+		if srv.owner != owner {
+			return nil, fmt.Errorf("statsd listen %s: already used by instance %q", addr, srv.owner)
+		}
+		if srv.shipTag != shipTag {
+			return nil, fmt.Errorf("statsd listen %s: already open with ship_tag %q", addr, srv.shipTag)
+		}
+		return srv, nil
+	}
+
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("statsd listen %s: %w", addr, err)
+	}
+
+	srv := &statsdServer{
+		counters: make(map[statsdKey]float64),
+		gauges:   make(map[statsdKey]float64),
+		timers:   make(map[statsdKey]*statsdTimer),
+		sets:     make(map[statsdKey]map[string]struct{}),
+		shipTag:  shipTag,
+		owner:    owner,
+	}
+	statsdServers[addr] = srv
+
+	log.Printf("📡 StatsD listening on udp %s", conn.LocalAddr())
+	go srv.serve(conn)
+	return srv, nil
+}
+
+func (s *statsdServer) serve(conn net.PacketConn) {
+	buf := make([]byte, 65535)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			log.Printf("⚠️ StatsD read error: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		s.mu.Lock()
+		s.packets++
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				if err := s.handleLine(line); err != nil {
+					s.bad++
+				}
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// handleLine parses `name:value|type[|@rate][|#tag:val,tag2]`. Caller holds s.mu.
+func (s *statsdServer) handleLine(line string) error {
+	// DogStatsD events and service checks aren't metrics
+	if strings.HasPrefix(line, "_e{") || strings.HasPrefix(line, "_sc|") {
+		return nil
+	}
+
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return fmt.Errorf("bad line")
+	}
+	parts := strings.Split(rest, "|")
+	if len(parts) < 2 {
+		return fmt.Errorf("bad line")
+	}
+	value, typ := parts[0], parts[1]
+
+	rate := 1.0
+	tags := map[string]string{}
+	for _, p := range parts[2:] {
+		switch {
+		case strings.HasPrefix(p, "@"):
+			if r, err := strconv.ParseFloat(p[1:], 64); err == nil && r > 0 && r <= 1 {
+				rate = r
+			}
+		case strings.HasPrefix(p, "#"):
+			for _, t := range strings.Split(p[1:], ",") {
+				k, v, _ := strings.Cut(t, ":")
+				if k != "" {
+					tags[k] = v
+				}
+			}
+		}
+	}
+
+	key := statsdKey{}
+	if s.shipTag != "" {
+		key.ship = tags[s.shipTag]
+		delete(tags, s.shipTag)
+	}
+	// Valueless tags ("#canary") still distinguish series
+	for k, v := range tags {
+		if v == "" {
+			tags[k] = k
+		}
+	}
+	key.id = labeledCargoID(name, tags)
+
+	if typ == "s" {
+		if s.sets[key] == nil {
+			s.sets[key] = make(map[string]struct{})
+		}
+		s.sets[key][value] = struct{}{}
+		return nil
+	}
+
+	// Gauges accept relative updates ("+3", "-2")
+	relative := typ == "g" && (strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-"))
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("bad value")
+	}
+
+	switch typ {
+	case "c":
+		// Counters are scaled up by the client's sample rate
+		s.counters[key] += v / rate
+	case "g":
+		if relative {
+			s.gauges[key] += v
+		} else {
+			s.gauges[key] = v
+		}
+	case "ms", "h", "d":
+		t := s.timers[key]
+		if t == nil {
+			t = &statsdTimer{min: v, max: v}
+			s.timers[key] = t
+		}
+		t.count += 1 / rate
+		t.observed++
+		t.sum += v
+		t.min = math.Min(t.min, v)
+		t.max = math.Max(t.max, v)
+		// Reservoir sampling, so percentiles cover the whole interval and
+		// not just its first statsdMaxTimerSamples values
+		if len(t.samples) < statsdMaxTimerSamples {
+			t.samples = append(t.samples, v)
+		} else if j := rand.Int64N(t.observed); j < statsdMaxTimerSamples {
+			t.samples[j] = v
+		}
+	default:
+		return fmt.Errorf("unknown type %q", typ)
+	}
+	return nil
+}
+
+// flush returns this interval's aggregates and resets counters, timers and sets.
+// Gauges keep their last value (like statsd) unless deleteGauges is set.
+func (s *statsdServer) flush(percentiles []float64, deleteGauges bool, shipPrefix string) []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ships := map[string]map[string]interface{}{"": {
+		"statsd_packets":   s.packets,
+		"statsd_bad_lines": s.bad,
+	}}
+	out := func(ship string) map[string]interface{} {
+		if ships[ship] == nil {
+			ships[ship] = make(map[string]interface{})
+		}
+		return ships[ship]
+	}
+
+	for k, v := range s.counters {
+		out(k.ship)[k.id] = v
+	}
+	for k, v := range s.gauges {
+		out(k.ship)[k.id] = v
+	}
+	for k, set := range s.sets {
+		out(k.ship)[k.id] = int64(len(set))
+	}
+	for k, t := range s.timers {
+		m := out(k.ship)
+		m[k.id+"_count"] = t.count
+		m[k.id+"_min"] = t.min
+		m[k.id+"_max"] = t.max
+		m[k.id+"_mean"] = t.sum / float64(t.observed)
+		m[k.id+"_sum"] = t.sum
+
+		sort.Float64s(t.samples)
+		for _, p := range percentiles {
+			m[k.id+"_"+quantileName(p/100)] = percentile(t.samples, p)
+		}
+	}
+
+	s.counters = make(map[statsdKey]float64)
+	s.timers = make(map[statsdKey]*statsdTimer)
+	s.sets = make(map[statsdKey]map[string]struct{})
+	s.packets, s.bad = 0, 0
+	if deleteGauges {
+		s.gauges = make(map[statsdKey]float64)
+	}
+
+	results := []map[string]interface{}{ships[""]}
+	for ship, m := range ships {
+		if ship != "" {
+			m["ship_id"] = shipPrefix + ship
+			results = append(results, m)
+		}
+	}
+	return results
+}
+
+// percentile uses nearest-rank on sorted samples.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
@@ -0,0 +1,380 @@
+package collectors
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const syslogDefaultListen = ":514"
+
+var syslogSeverities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris_cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+type syslogMessage struct {
+	facility, severity int
+	host, msg          string
+}
+
+// syslogHostStats aggregates messages from one sending host between ticks.
+type syslogHostStats struct {
+	counts map[string]int64
+	values map[string]*valueStats
+}
+
+type syslogServer struct {
+	mu       sync.Mutex
+	hosts    map[string]*syslogHostStats
+	patterns []logPattern
+	byIP     bool
+	settings string // match.* and ship_by the server was opened with
+	owner    string // instance that opened it; a flush drains every host
+	bad      int64
+}
+
+var (
+	syslogServersMu sync.Mutex
+	syslogServers   = make(map[string]*syslogServer) // keyed by protocol + listen address
+)
+
+// SyslogCollector receives RFC3164/RFC5424 syslog over UDP and/or TCP and
+// reports per-host message counts by severity and facility. Each sending
+// host becomes its own ship. match.<name>=<regex> params work like logtail.
+func SyslogCollector(params map[string]string) ([]map[string]interface{}, error) {
+	addr := params["listen"]
+	if addr == "" {
+		addr = syslogDefaultListen
+	}
+	proto := params["protocol"]
+	if proto == "" {
+		proto = "udp"
+	}
+
+	srv, err := getSyslogServer(proto, addr, params[InstanceParam], params)
+	if err != nil {
+		return nil, err
+	}
+	return srv.flush(params["ship_prefix"]), nil
+}
+
+func getSyslogServer(proto, addr, owner string, params map[string]string) (*syslogServer, error) {
+	syslogServersMu.Lock()
+	defer syslogServersMu.Unlock()
+
+	key := proto + "://" + addr
+	settings := syslogSettings(params)
+	if srv, ok := syslogServers[key]; ok {
+		// Patterns and ship_by are fixed when the server opens, and a second
+		// instance would only see the hosts the first one didn't drain
+		if srv.owner != owner {
+			return nil, fmt.Errorf("syslog listen %s: already used by instance %q", key, srv.owner)
+		}
+		if srv.settings != settings {
+			return nil, fmt.Errorf("syslog listen %s: match.* or ship_by changed, restart to apply", key)
+		}
+		return srv, nil
+	}
+
+	if proto != "udp" && proto != "tcp" && proto != "both" {
+		return nil, fmt.Errorf("unknown syslog protocol %q (use udp, tcp or both)", proto)
+	}
+
+	patterns, err := logPatterns(params)
+	if err != nil {
+		return nil, err
+	}
+	srv := &syslogServer{
+		hosts:    make(map[string]*syslogHostStats),
+		patterns: patterns,
+		byIP:     params["ship_by"] == "ip",
+		settings: settings,
+		owner:    owner,
+	}
+
+	// Bind everything before serving, so a failed TCP bind doesn't leave the
+	// UDP socket open (and the next tick failing with "address in use")
+	var conn net.PacketConn
+	var ln net.Listener
+	if proto == "udp" || proto == "both" {
+		conn, err = net.ListenPacket("udp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("syslog listen udp %s: %w", addr, err)
+		}
+	}
+	if proto == "tcp" || proto == "both" {
+		ln, err = net.Listen("tcp", addr)
+		if err != nil {
+			if conn != nil {
+				conn.Close()
+			}
+			return nil, fmt.Errorf("syslog listen tcp %s: %w", addr, err)
+		}
+	}
+
+	if conn != nil {
+		log.Printf("📜 Syslog listening on udp %s", conn.LocalAddr())
+		go srv.serveUDP(conn)
+	}
+	if ln != nil {
+		log.Printf("📜 Syslog listening on tcp %s", ln.Addr())
+		go srv.serveTCP(ln)
+	}
+	syslogServers[key] = srv
+	return srv, nil
+}
+
+// syslogSettings sums up the params a running server was built from.
+func syslogSettings(params map[string]string) string {
+	var parts []string
+	for k, v := range params {
+		if strings.HasPrefix(k, "match.") || k == "ship_by" {
+			parts = append(parts, k+"="+v)
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "\n")
+}
+
+func (s *syslogServer) serveUDP(conn net.PacketConn) {
+	buf := make([]byte, 65535)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			log.Printf("⚠️ Syslog read error: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		s.handle(string(buf[:n]), hostOf(from))
+	}
+}
+
+func (s *syslogServer) serveTCP(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			log.Printf("⚠️ Syslog accept error: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn handles both TCP framings: octet counting ("LEN MSG", RFC6587)
+// and newline-delimited messages.
+func (s *syslogServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+	ip := hostOf(conn.RemoteAddr())
+	r := bufio.NewReader(conn)
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(10 * time.Minute))
+
+		first, err := r.Peek(1)
+		if err != nil {
+			return
+		}
+
+		var msg string
+		if first[0] >= '1' && first[0] <= '9' {
+			lenStr, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(lenStr))
+			if err != nil || n <= 0 || n > 1<<20 {
+				return
+			}
+			b := make([]byte, n)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return
+			}
+			msg = string(b)
+		} else {
+			line, err := r.ReadString('\n')
+			if err != nil && line == "" {
+				return
+			}
+			msg = line
+		}
+
+		s.handle(msg, ip)
+	}
+}
+
+func hostOf(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+func (s *syslogServer) handle(raw, ip string) {
+	raw = strings.TrimRight(raw, "\r\n\x00")
+	if raw == "" {
+		return
+	}
+
+	m, ok := parseSyslog(raw)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !ok {
+		s.bad++
+		return
+	}
+
+	host := m.host
+	if s.byIP || host == "" || host == "-" {
+		host = ip
+	}
+
+	st := s.hosts[host]
+	if st == nil {
+		st = &syslogHostStats{counts: make(map[string]int64), values: make(map[string]*valueStats)}
+		s.hosts[host] = st
+	}
+
+	st.counts["syslog_messages"]++
+	st.counts["syslog_severity_"+syslogSeverities[m.severity]]++
+	if m.facility < len(syslogFacilities) {
+		st.counts["syslog_facility_"+syslogFacilities[m.facility]]++
+	}
+
+	for _, p := range s.patterns {
+		match := p.re.FindStringSubmatch(m.msg)
+		if match == nil {
+			continue
+		}
+		st.counts["syslog_"+p.name+"_count"]++
+		for i, group := range p.re.SubexpNames() {
+			if group == "" || i >= len(match) {
+				continue
+			}
+			if v, err := strconv.ParseFloat(match[i], 64); err == nil {
+				id := p.name + "_" + group
+				if st.values[id] == nil {
+					st.values[id] = &valueStats{}
+				}
+				st.values[id].add(v)
+			}
+		}
+	}
+}
+
+// flush returns one ship per sending host and resets the counts.
+func (s *syslogServer) flush(shipPrefix string) []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := []map[string]interface{}{{
+		"syslog_hosts":        int64(len(s.hosts)),
+		"syslog_parse_errors": s.bad,
+	}}
+
+	for host, st := range s.hosts {
+		m := map[string]interface{}{"ship_id": shipPrefix + host}
+		for k, v := range st.counts {
+			m[k] = v
+		}
+		for id, v := range st.values {
+			m["syslog_"+id+"_avg"] = v.sum / float64(v.count)
+			m["syslog_"+id+"_min"] = v.min
+			m["syslog_"+id+"_max"] = v.max
+			m["syslog_"+id+"_sum"] = v.sum
+		}
+		results = append(results, m)
+	}
+
+	s.hosts = make(map[string]*syslogHostStats)
+	s.bad = 0
+	return results
+}
+
+// parseSyslog understands RFC5424 ("<PRI>1 TS HOST APP PROCID MSGID SD MSG")
+// and the looser RFC3164 ("<PRI>Mmm dd hh:mm:ss HOST TAG: MSG").
+func parseSyslog(raw string) (syslogMessage, bool) {
+	m := syslogMessage{facility: 1, severity: 5} // RFC3164 default PRI 13 (user.notice)
+
+	if strings.HasPrefix(raw, "<") {
+		end := strings.IndexByte(raw, '>')
+		if end < 2 || end > 4 {
+			return m, false
+		}
+		pri, err := strconv.Atoi(raw[1:end])
+		if err != nil || pri < 0 || pri > 191 {
+			return m, false
+		}
+		m.facility, m.severity = pri/8, pri%8
+		raw = raw[end+1:]
+	}
+
+	if strings.HasPrefix(raw, "1 ") {
+		fields := strings.SplitN(raw[2:], " ", 6)
+		if len(fields) < 5 {
+			return m, false
+		}
+		m.host = fields[1]
+		if len(fields) == 6 {
+			m.msg = skipStructuredData(fields[5])
+		}
+		return m, true
+	}
+
+	// RFC3164: optional "Mmm dd hh:mm:ss " timestamp, then host
+	if len(raw) >= 16 {
+		if _, err := time.Parse(time.Stamp, raw[:15]); err == nil {
+			raw = raw[16:]
+			if host, rest, ok := strings.Cut(raw, " "); ok && !strings.HasSuffix(host, ":") {
+				m.host, raw = host, rest
+			}
+		}
+	}
+
+	// Strip the "tag[pid]: " prefix so regexes only see the message
+	if tag, rest, ok := strings.Cut(raw, ": "); ok && !strings.Contains(tag, " ") {
+		raw = rest
+	}
+	m.msg = raw
+	return m, true
+}
+
+// skipStructuredData drops the RFC5424 SD field ("-" or "[id k="v"]...") before MSG.
+func skipStructuredData(s string) string {
+	if strings.HasPrefix(s, "-") {
+		return strings.TrimPrefix(strings.TrimPrefix(s, "-"), " ")
+	}
+
+	inQuote := false
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && inQuote:
+			i++
+		case c == '"':
+			inQuote = !inQuote
+		case c == '[' && !inQuote:
+			depth++
+		case c == ']' && !inQuote:
+			depth--
+			if depth == 0 && (i+1 == len(s) || s[i+1] != '[') {
+				return strings.TrimPrefix(s[i+1:], " ")
+			}
+		}
+	}
+	return s
+}
@@ -82,6 +82,13 @@ func SystemCollector(params map[string]string) ([]map[string]interface{}, error)
 		snapshot["process_count"] = len(pids)
 	}
 
+	// --- Hardware Sensors (Linux sysfs) ---
+	if paramBool(params, "sensors") {
+		for k, v := range readSensors("/sys") {
+			snapshot[k] = v
+		}
+	}
+
 	// ✅ CORRECT RETURN: Wrap the single snapshot in a slice
 	return []map[string]interface{}{snapshot}, nil
 }
@@ -0,0 +1,202 @@
+//go:build linux
+
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Reuse one D-Bus connection across ticks, same as the Docker client
+var (
+	systemdConn *dbus.Conn
+	systemdMu   sync.Mutex
+)
+
+func getSystemdConn() (*dbus.Conn, error) {
+	systemdMu.Lock()
+	defer systemdMu.Unlock()
+
+	if systemdConn != nil && systemdConn.Connected() {
+		return systemdConn, nil
+	}
+
+	// godbus closes the connection when its context ends, so it gets one that
+	// doesn't; the per-tick timeout only applies to individual calls
+	conn, err := dbus.NewSystemConnectionContext(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	systemdConn = conn
+	return systemdConn, nil
+}
+
+func resetSystemdConn() {
+	systemdMu.Lock()
+	defer systemdMu.Unlock()
+	if systemdConn != nil {
+		systemdConn.Close()
+		systemdConn = nil
+	}
+}
+
+// SystemdCollector reports the state of systemd units over D-Bus.
+// Param "units" is a comma separated list of names or globs, e.g. "nginx.service,docker*".
+func SystemdCollector(params map[string]string) ([]map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), paramMillis(params, "timeout_ms", 10*time.Second))
+	defer cancel()
+
+	conn, err := getSystemdConn()
+	if err != nil {
+		return nil, fmt.Errorf("systemd_dbus_error: %w", err)
+	}
+
+	// Exact names are looked up directly so missing units still report (as not-found);
+	// globs go through the pattern API, which only returns loaded units.
+	var names, patterns []string
+	for _, u := range splitList(params["units"]) {
+		if strings.ContainsAny(u, "*?[") {
+			patterns = append(patterns, u)
+		} else {
+			names = append(names, u)
+		}
+	}
+
+	var units []dbus.UnitStatus
+	if len(names) > 0 {
+		found, err := conn.ListUnitsByNamesContext(ctx, names)
+		if err != nil {
+			resetSystemdConn()
+			return nil, fmt.Errorf("systemd_list_error: %w", err)
+		}
+		units = append(units, found...)
+	}
+	if len(patterns) > 0 {
+		found, err := conn.ListUnitsByPatternsContext(ctx, nil, patterns)
+		if err != nil {
+			resetSystemdConn()
+			return nil, fmt.Errorf("systemd_list_error: %w", err)
+		}
+		units = append(units, found...)
+	}
+
+	summary := map[string]interface{}{}
+	if failed, err := conn.ListUnitsFilteredContext(ctx, []string{"failed"}); err == nil {
+		summary["systemd_system_failed_units"] = int64(len(failed))
+	}
+
+	var active, failedCount int64
+	perUnit := make(map[string]map[string]interface{})
+
+	for _, u := range units {
+		if _, seen := perUnit[u.Name]; seen {
+			continue
+		}
+
+		m := map[string]interface{}{
+			"systemd_unit_loaded": boolToInt(u.LoadState == "loaded"),
+			"systemd_unit_active": boolToInt(u.ActiveState == "active"),
+			"systemd_unit_failed": boolToInt(u.ActiveState == "failed"),
+			"systemd_unit_state":  unitStateToInt(u.ActiveState),
+		}
+		if u.ActiveState == "active" {
+			active++
+		}
+		if u.ActiveState == "failed" {
+			failedCount++
+		}
+
+		if strings.HasSuffix(u.Name, ".service") && u.LoadState == "loaded" {
+			addServiceProperties(ctx, conn, u.Name, m)
+		}
+
+		perUnit[u.Name] = m
+	}
+
+	summary["systemd_units_total"] = int64(len(perUnit))
+	summary["systemd_units_active"] = active
+	summary["systemd_units_failed"] = failedCount
+
+	return fanOut(params, "unit_mode", summary, perUnit), nil
+}
+
+// addServiceProperties adds restart count, main PID, and memory of both the
+// whole unit (its cgroup) and the main process alone.
+func addServiceProperties(ctx context.Context, conn *dbus.Conn, name string, m map[string]interface{}) {
+	props, err := conn.GetUnitTypePropertiesContext(ctx, name, "Service")
+	if err != nil {
+		return
+	}
+
+	if v, ok := props["NRestarts"].(uint32); ok {
+		m["systemd_unit_restarts"] = int64(v)
+	}
+	if v, ok := props["MainPID"].(uint32); ok {
+		m["systemd_unit_main_pid"] = int64(v)
+		if v != 0 {
+			if p, err := process.NewProcessWithContext(ctx, int32(v)); err == nil {
+				if mem, err := p.MemoryInfoWithContext(ctx); err == nil {
+					m["systemd_unit_main_pid_memory_bytes"] = int64(mem.RSS)
+				}
+			}
+		}
+	}
+
+	// Prefer reading the cgroup directly; MemoryCurrent needs MemoryAccounting=yes
+	if cg, ok := props["ControlGroup"].(string); ok && cg != "" {
+		if mem, ok := cgroupMemoryBytes(cg); ok {
+			m["systemd_unit_memory_bytes"] = mem
+			return
+		}
+	}
+	// systemd reports "unknown" as the max uint64
+	if v, ok := props["MemoryCurrent"].(uint64); ok && v != ^uint64(0) {
+		m["systemd_unit_memory_bytes"] = int64(v)
+	}
+}
+
+// cgroupMemoryBytes reads current memory usage for a cgroup path (v2, then v1).
+func cgroupMemoryBytes(cg string) (int64, bool) {
+	candidates := []string{
+		filepath.Join("/sys/fs/cgroup", cg, "memory.current"),
+		filepath.Join("/sys/fs/cgroup/memory", cg, "memory.usage_in_bytes"),
+	}
+	for _, p := range candidates {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		if v, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err == nil {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+func unitStateToInt(s string) int64 {
+	switch s {
+	case "inactive":
+		return 0
+	case "active":
+		return 1
+	case "activating":
+		return 2
+	case "deactivating":
+		return 3
+	case "reloading":
+		return 4
+	case "failed":
+		return 5
+	default:
+		return -1
+	}
+}
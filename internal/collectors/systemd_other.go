@@ -0,0 +1,10 @@
+//go:build !linux
+
+package collectors
+
+import "fmt"
+
+// SystemdCollector is only available on Linux.
+func SystemdCollector(params map[string]string) ([]map[string]interface{}, error) {
+	return nil, fmt.Errorf("systemd source is only supported on linux")
+}
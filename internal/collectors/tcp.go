@@ -0,0 +1,75 @@
+package collectors
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+// TCPCollector checks that host:port targets accept connections.
+// Optionally sends a payload and/or waits for a banner containing "expect",
+// e.g. --param expect="SSH-2.0" for an SSH server.
+func TCPCollector(params map[string]string) ([]map[string]interface{}, error) {
+	timeout := paramMillis(params, "timeout_ms", 5*time.Second)
+	send := unescapeParam(params["send"])
+	expect := params["expect"]
+
+	return probeTargets(params, "tcp", "tcp_up", func(target string) map[string]interface{} {
+		result := map[string]interface{}{
+			"tcp_up":         int64(0),
+			"tcp_connect_ms": int64(0),
+		}
+
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", target, timeout)
+		if err != nil {
+			return result
+		}
+		defer conn.Close()
+		result["tcp_connect_ms"] = time.Since(start).Milliseconds()
+
+		if send == "" && expect == "" {
+			result["tcp_up"] = int64(1)
+			return result
+		}
+
+		conn.SetDeadline(time.Now().Add(timeout))
+		if send != "" {
+			if _, err := conn.Write([]byte(send)); err != nil {
+				return result
+			}
+		}
+
+		if expect == "" {
+			result["tcp_up"] = int64(1)
+			return result
+		}
+
+		// Read until the expected string shows up, the peer closes, or we time out
+		respStart := time.Now()
+		var got strings.Builder
+		buf := make([]byte, 4096)
+		matched := false
+		for got.Len() < 64*1024 {
+			n, err := conn.Read(buf)
+			got.Write(buf[:n])
+			if strings.Contains(got.String(), expect) {
+				matched = true
+				break
+			}
+			if err != nil {
+				break
+			}
+		}
+
+		result["tcp_response_ms"] = time.Since(respStart).Milliseconds()
+		result["tcp_expect_ok"] = boolToInt(matched)
+		result["tcp_up"] = boolToInt(matched)
+		return result
+	})
+}
+
+// unescapeParam lets payload params contain \r, \n and \t (e.g. "PING\r\n").
+func unescapeParam(s string) string {
+	return strings.NewReplacer(`\r`, "\r", `\n`, "\n", `\t`, "\t").Replace(s)
+}
@@ -0,0 +1,156 @@
+package collectors
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// TLSCertCollector connects to host:port targets and inspects the certificate
+// chain the server presents. We skip verification during the handshake so
+// expired or mismatched certs are still reported instead of just failing.
+//
+//	--param targets=example.com,mail.example.com:25
+//	--param starttls=smtp      (upgrade plain SMTP connections first)
+func TLSCertCollector(params map[string]string) ([]map[string]interface{}, error) {
+	timeout := paramMillis(params, "timeout_ms", 10*time.Second)
+	starttls := params["starttls"]
+	if starttls != "" && starttls != "smtp" {
+		return nil, fmt.Errorf("unsupported starttls %q (only smtp)", starttls)
+	}
+
+	return probeTargets(params, "tls", "tls_up", func(target string) map[string]interface{} {
+		result := map[string]interface{}{
+			"tls_up": int64(0),
+		}
+
+		defaultPort := "443"
+		if starttls == "smtp" {
+			defaultPort = "25"
+		}
+		host, port := splitTarget(target, defaultPort)
+		serverName := host
+		if sn := params["server_name"]; sn != "" {
+			serverName = sn
+		}
+
+		certs, state, err := fetchCertificates(net.JoinHostPort(host, port), serverName, starttls, timeout)
+		if err != nil || len(certs) == 0 {
+			return result
+		}
+		leaf := certs[0]
+
+		result["tls_up"] = int64(1)
+		result["tls_days_until_expiry"] = time.Until(leaf.NotAfter).Hours() / 24
+		result["tls_version"] = tlsVersionNumber(state.Version)
+
+		// Earliest expiry anywhere in the chain (an expiring intermediate breaks it too)
+		chainExpiry := leaf.NotAfter
+		minBits := int64(-1)
+		for _, c := range certs {
+			if c.NotAfter.Before(chainExpiry) {
+				chainExpiry = c.NotAfter
+			}
+			if bits := publicKeyBits(c); bits > 0 && (minBits < 0 || bits < minBits) {
+				minBits = bits
+			}
+		}
+		result["tls_chain_days_until_expiry"] = time.Until(chainExpiry).Hours() / 24
+		result["tls_min_key_bits"] = minBits
+
+		// Chain validity against system roots, using whatever intermediates the server sent
+		intermediates := x509.NewCertPool()
+		for _, c := range certs[1:] {
+			intermediates.AddCert(c)
+		}
+		_, verr := leaf.Verify(x509.VerifyOptions{Intermediates: intermediates})
+		result["tls_chain_valid"] = boolToInt(verr == nil)
+		result["tls_hostname_match"] = boolToInt(leaf.VerifyHostname(serverName) == nil)
+
+		return result
+	})
+}
+
+func fetchCertificates(addr, serverName, starttls string, timeout time.Duration) ([]*x509.Certificate, tls.ConnectionState, error) {
+	cfg := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true, // we verify ourselves so we can report *why* it's bad
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, tls.ConnectionState{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if starttls == "smtp" {
+		c, err := smtp.NewClient(conn, serverName)
+		if err != nil {
+			return nil, tls.ConnectionState{}, err
+		}
+		if err := c.Hello("lighthouse"); err != nil {
+			return nil, tls.ConnectionState{}, err
+		}
+		if err := c.StartTLS(cfg); err != nil {
+			return nil, tls.ConnectionState{}, err
+		}
+		state, _ := c.TLSConnectionState()
+		c.Quit()
+		return state.PeerCertificates, state, nil
+	}
+
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, tls.ConnectionState{}, err
+	}
+	state := tlsConn.ConnectionState()
+	return state.PeerCertificates, state, nil
+}
+
+// splitTarget splits host[:port], taking defaultPort when there is none. A
+// bare IPv6 address may be bracketed ("[::1]") or not ("::1").
+func splitTarget(target, defaultPort string) (host, port string) {
+	if h, p, err := net.SplitHostPort(target); err == nil {
+		return h, p
+	}
+	if strings.HasPrefix(target, "[") && strings.HasSuffix(target, "]") {
+		return target[1 : len(target)-1], defaultPort
+	}
+	return target, defaultPort
+}
+
+// publicKeyBits returns the key size, e.g. 2048 for RSA-2048 or 256 for P-256.
+func publicKeyBits(c *x509.Certificate) int64 {
+	switch k := c.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return int64(k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return int64(k.Curve.Params().BitSize)
+	case ed25519.PublicKey:
+		return 256
+	}
+	return 0
+}
+
+// tlsVersionNumber maps tls.VersionTLS12 to 1.2 etc.
+func tlsVersionNumber(v uint16) float64 {
+	switch v {
+	case tls.VersionTLS10:
+		return 1.0
+	case tls.VersionTLS11:
+		return 1.1
+	case tls.VersionTLS12:
+		return 1.2
+	case tls.VersionTLS13:
+		return 1.3
+	}
+	return 0
+}
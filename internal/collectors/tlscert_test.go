@@ -0,0 +1,54 @@
+package collectors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTLSCertCollector(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	target := strings.TrimPrefix(srv.URL, "https://")
+
+	res, err := TLSCertCollector(map[string]string{"targets": target, "server_name": "example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMetrics(t, res[0], map[string]interface{}{
+		"tls_targets":    int64(1),
+		"tls_targets_up": int64(1),
+	})
+	m := res[1]
+	assertMetrics(t, m, map[string]interface{}{
+		"tls_up":             int64(1),
+		"tls_version":        1.3,
+		"tls_chain_valid":    int64(0), // httptest's CA isn't a system root
+		"tls_hostname_match": int64(1),
+	})
+	if d, ok := m["tls_days_until_expiry"].(float64); !ok || d <= 0 {
+		t.Errorf("tls_days_until_expiry = %v, want > 0", m["tls_days_until_expiry"])
+	}
+	for k, v := range m {
+		if _, ok := v.(string); ok && k != "ship_id" {
+			t.Errorf("%s = %q: only numbers are sent", k, v)
+		}
+	}
+}
+
+func TestSplitTarget(t *testing.T) {
+	for target, want := range map[string][2]string{
+		"example.com":      {"example.com", "443"},
+		"example.com:8443": {"example.com", "8443"},
+		"[::1]":            {"::1", "443"},
+		"[::1]:8443":       {"::1", "8443"},
+		"::1":              {"::1", "443"},
+		"10.0.0.5":         {"10.0.0.5", "443"},
+	} {
+		host, port := splitTarget(target, "443")
+		if host != want[0] || port != want[1] {
+			t.Errorf("splitTarget(%q) = %q, %q, want %q, %q", target, host, port, want[0], want[1])
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package collectors
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// ttnInbox buffers decoded uplinks between collector ticks.
+type ttnInbox struct {
+	mu      sync.Mutex
+	rows    []map[string]interface{}
+	max     int
+	dropped int64
+}
+
+var (
+	ttnInboxesMu sync.Mutex
+	ttnInboxes   = make(map[string]*ttnInbox)
+)
+
+// ttnUplink is the subset of a The Things Stack v3 uplink message we forward.
+type ttnUplink struct {
+	EndDeviceIDs struct {
+		DeviceID string `json:"device_id"`
+		DevEUI   string `json:"dev_eui"`
+	} `json:"end_device_ids"`
+	ReceivedAt    string `json:"received_at"`
+	UplinkMessage struct {
+		FPort          int64                  `json:"f_port"`
+		FCnt           int64                  `json:"f_cnt"`
+		FrmPayload     string                 `json:"frm_payload"`
+		DecodedPayload map[string]interface{} `json:"decoded_payload"`
+		RxMetadata     []struct {
+			GatewayIDs struct {
+				GatewayID string `json:"gateway_id"`
+			} `json:"gateway_ids"`
+			RSSI        *float64 `json:"rssi"`
+			ChannelRSSI *float64 `json:"channel_rssi"`
+			SNR         *float64 `json:"snr"`
+		} `json:"rx_metadata"`
+		Settings struct {
+			DataRate struct {
+				Lora struct {
+					Bandwidth       int64 `json:"bandwidth"`
+					SpreadingFactor int64 `json:"spreading_factor"`
+				} `json:"lora"`
+			} `json:"data_rate"`
+			Frequency string `json:"frequency"`
+		} `json:"settings"`
+		ConsumedAirtime string `json:"consumed_airtime"`
+	} `json:"uplink_message"`
+}
+
+// TTNCollector subscribes to a The Things Stack application's MQTT
+// integration and forwards every uplink received since the last tick as
+// one raw payload per message: the decoded payload fields plus radio
+// metadata, with the device ID as ship_id. Use it with --type ttn.
+//
+//	--param app_id=my-app --param api_key=NNSXS....
+//	--param region=eu1                      (eu1, nam1, au1; default eu1)
+//	--param broker=tcp://localhost:1883     (self-hosted stacks)
+//	--param devices=sensor-*                (device ID globs)
+func TTNCollector(params map[string]string) ([]map[string]interface{}, error) {
+	appID, apiKey := params["app_id"], params["api_key"]
+	if appID == "" || apiKey == "" {
+		return nil, fmt.Errorf("missing 'app_id' or 'api_key' param")
+	}
+
+	// MQTT username is "<app>@<tenant>"; The Things Network's tenant is "ttn"
+	username := appID
+	if !strings.Contains(username, "@") {
+		tenant := params["tenant"]
+		if tenant == "" {
+			tenant = "ttn"
+		}
+		username += "@" + tenant
+	}
+
+	broker := params["broker"]
+	if broker == "" {
+		region := params["region"]
+		if region == "" {
+			region = "eu1"
+		}
+		broker = "ssl://" + region + ".cloud.thethings.network:8883"
+	}
+
+	// One client and inbox per instance: each drains its own uplinks, so
+	// instances with different devices filters don't steal each other's rows
+	key := "ttn|" + paramsHash(params)
+	inbox := getTTNInbox(key, params)
+	topic := "v3/" + username + "/devices/+/up"
+	client := getMQTTClient(key, newMQTTOptions(params, broker, username, apiKey), map[string]byte{topic: 1}, inbox.receive)
+
+	devices := splitList(params["devices"])
+	prefix := params["ship_prefix"]
+
+	inbox.mu.Lock()
+	rows, dropped := inbox.rows, inbox.dropped
+	inbox.rows, inbox.dropped = nil, 0
+	inbox.mu.Unlock()
+	if dropped > 0 {
+		log.Printf("⚠️ TTN buffer full, dropped %d uplinks (raise max_buffer or shorten the interval)", dropped)
+	}
+
+	out := []map[string]interface{}{}
+	for _, row := range rows {
+		id, _ := row["ship_id"].(string)
+		if len(devices) > 0 && !globAny(devices, id) {
+			continue
+		}
+		row["ship_id"] = prefix + id
+		out = append(out, row)
+	}
+
+	if len(out) == 0 && !client.IsConnectionOpen() {
+		return nil, fmt.Errorf("not connected to %s", broker)
+	}
+	return out, nil
+}
+
+func getTTNInbox(key string, params map[string]string) *ttnInbox {
+	ttnInboxesMu.Lock()
+	defer ttnInboxesMu.Unlock()
+
+	if in, ok := ttnInboxes[key]; ok {
+		return in
+	}
+	in := &ttnInbox{max: paramInt(params, "max_buffer", listenMaxBuffer)}
+	ttnInboxes[key] = in
+	return in
+}
+
+func (in *ttnInbox) receive(_ mqtt.Client, msg mqtt.Message) {
+	var up ttnUplink
+	if err := json.Unmarshal(msg.Payload(), &up); err != nil || up.EndDeviceIDs.DeviceID == "" {
+		return
+	}
+	row := ttnRow(&up)
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if len(in.rows) >= in.max {
+		in.dropped++
+		return
+	}
+	in.rows = append(in.rows, row)
+}
+
+// ttnRow flattens an uplink. Radio metadata wins over decoded fields with the
+// same name; the gateway with the strongest RSSI provides rssi/snr.
+func ttnRow(up *ttnUplink) map[string]interface{} {
+	u := &up.UplinkMessage
+	row := make(map[string]interface{}, len(u.DecodedPayload)+12)
+	for k, v := range u.DecodedPayload {
+		row[k] = v
+	}
+	if u.DecodedPayload == nil && u.FrmPayload != "" {
+		row["frm_payload"] = u.FrmPayload // no payload formatter configured
+	}
+
+	row["ship_id"] = up.EndDeviceIDs.DeviceID
+	row["dev_eui"] = up.EndDeviceIDs.DevEUI
+	row["received_at"] = up.ReceivedAt
+	row["f_port"] = u.FPort
+	row["f_cnt"] = u.FCnt
+	row["gateways"] = int64(len(u.RxMetadata))
+
+	best, bestRSSI := -1, 0.0
+	for i, md := range u.RxMetadata {
+		rssi := md.RSSI
+		if rssi == nil {
+			rssi = md.ChannelRSSI
+		}
+		if rssi != nil && (best < 0 || *rssi > bestRSSI) {
+			best, bestRSSI = i, *rssi
+		}
+	}
+	if best >= 0 {
+		row["rssi"] = bestRSSI
+		row["gateway_id"] = u.RxMetadata[best].GatewayIDs.GatewayID
+		if snr := u.RxMetadata[best].SNR; snr != nil {
+			row["snr"] = *snr
+		}
+	}
+
+	if sf := u.Settings.DataRate.Lora.SpreadingFactor; sf > 0 {
+		row["spreading_factor"] = sf
+		row["bandwidth_hz"] = u.Settings.DataRate.Lora.Bandwidth
+	}
+	if f, err := strconv.ParseInt(u.Settings.Frequency, 10, 64); err == nil {
+		row["frequency_hz"] = f
+	}
+	if d, err := time.ParseDuration(u.ConsumedAirtime); err == nil {
+		row["airtime_ms"] = float64(d.Microseconds()) / 1000
+	}
+	return row
+}
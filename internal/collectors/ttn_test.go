@@ -0,0 +1,231 @@
+package collectors
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+)
+
+// fakeBroker is just enough of an MQTT 3.1.1 broker for one client: it
+// accepts the connection, acknowledges subscriptions (reporting the topics
+// on subscribed) and forwards whatever is sent on publish.
+type fakeBroker struct {
+	addr       string
+	subscribed chan string
+	publish    chan *packets.PublishPacket
+}
+
+func startFakeBroker(t *testing.T) *fakeBroker {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeBroker{
+		addr:       ln.Addr().String(),
+		subscribed: make(chan string, 8),
+		publish:    make(chan *packets.PublishPacket, 8),
+	}
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		close(done)
+		ln.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn, done)
+		}
+	}()
+	return b
+}
+
+func (b *fakeBroker) serve(conn net.Conn, done chan struct{}) {
+	defer conn.Close()
+
+	// Outgoing publishes are written from here so they never interleave with acks
+	out := make(chan packets.ControlPacket, 8)
+	go func() {
+		for {
+			select {
+			case p := <-out:
+				p.Write(conn)
+			case p := <-b.publish:
+				p.Write(conn)
+			case <-done:
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	for {
+		cp, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		switch p := cp.(type) {
+		case *packets.ConnectPacket:
+			out <- packets.NewControlPacket(packets.Connack)
+		case *packets.SubscribePacket:
+			ack := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+			ack.MessageID = p.MessageID
+			ack.ReturnCodes = p.Qoss
+			out <- ack
+			for _, topic := range p.Topics {
+				b.subscribed <- topic
+			}
+		case *packets.PingreqPacket:
+			out <- packets.NewControlPacket(packets.Pingresp)
+		case *packets.DisconnectPacket:
+			return
+		}
+	}
+}
+
+func (b *fakeBroker) send(topic, payload string) {
+	p := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+	p.TopicName = topic
+	p.Payload = []byte(payload)
+	b.publish <- p
+}
+
+const ttnTestUplink = `{
+  "end_device_ids": {"device_id": "sensor-1", "dev_eui": "70B3D57ED0000001"},
+  "received_at": "2024-05-01T12:00:00.123Z",
+  "uplink_message": {
+    "f_port": 2,
+    "f_cnt": 42,
+    "frm_payload": "AQID",
+    "decoded_payload": {"temperature": 21.5, "humidity": 60, "rssi": 999},
+    "rx_metadata": [
+      {"gateway_ids": {"gateway_id": "gw-far"}, "rssi": -118, "snr": -7.5},
+      {"gateway_ids": {"gateway_id": "gw-near"}, "rssi": -71, "snr": 9.25},
+      {"gateway_ids": {"gateway_id": "gw-packet-broker"}, "channel_rssi": -90}
+    ],
+    "settings": {
+      "data_rate": {"lora": {"bandwidth": 125000, "spreading_factor": 7}},
+      "frequency": "868100000"
+    },
+    "consumed_airtime": "0.061696s"
+  }
+}`
+
+const ttnTestOtherDevice = `{
+  "end_device_ids": {"device_id": "tracker-9"},
+  "uplink_message": {"f_port": 1, "decoded_payload": {"lat": 1}}
+}`
+
+func TestTTNCollectorBroker(t *testing.T) {
+	broker := startFakeBroker(t)
+	params := map[string]string{
+		"app_id":      "my-app",
+		"api_key":     "NNSXS.TEST",
+		"broker":      "tcp://" + broker.addr,
+		"devices":     "sensor-*",
+		"ship_prefix": "ttn-",
+	}
+	key := "ttn|" + paramsHash(params)
+	t.Cleanup(func() {
+		mqttClientsMu.Lock()
+		if c, ok := mqttClients[key]; ok {
+			c.Disconnect(0)
+			delete(mqttClients, key)
+		}
+		mqttClientsMu.Unlock()
+		ttnInboxesMu.Lock()
+		delete(ttnInboxes, key)
+		ttnInboxesMu.Unlock()
+	})
+
+	// The first tick starts the client; nothing has arrived yet
+	TTNCollector(params)
+
+	select {
+	case topic := <-broker.subscribed:
+		if want := "v3/my-app@ttn/devices/+/up"; topic != want {
+			t.Fatalf("subscribed to %q, want %q", topic, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("client never subscribed")
+	}
+
+	broker.send("v3/my-app@ttn/devices/tracker-9/up", ttnTestOtherDevice)
+	broker.send("v3/my-app@ttn/devices/sensor-1/up", "not json")
+	broker.send("v3/my-app@ttn/devices/sensor-1/up", ttnTestUplink)
+
+	var rows []map[string]interface{}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(rows) == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		got, err := TTNCollector(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, got...)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1 (tracker-9 filtered out): %v", len(rows), rows)
+	}
+
+	assertMetrics(t, rows[0], map[string]interface{}{
+		"ship_id":          "ttn-sensor-1",
+		"dev_eui":          "70B3D57ED0000001",
+		"received_at":      "2024-05-01T12:00:00.123Z",
+		"temperature":      21.5,
+		"humidity":         60.0,
+		"f_port":           int64(2),
+		"f_cnt":            int64(42),
+		"gateways":         int64(3),
+		"rssi":             -71.0, // radio metadata wins over the decoded field
+		"snr":              9.25,
+		"gateway_id":       "gw-near",
+		"spreading_factor": int64(7),
+		"bandwidth_hz":     int64(125000),
+		"frequency_hz":     int64(868100000),
+		"airtime_ms":       61.696,
+	})
+	if _, ok := rows[0]["frm_payload"]; ok {
+		t.Error("frm_payload sent alongside a decoded payload")
+	}
+
+	// Drained: the next tick is empty
+	if got, err := TTNCollector(params); err != nil || len(got) != 0 {
+		t.Errorf("second tick = %v, %v; want no rows", got, err)
+	}
+}
+
+func TestTTNRowFallbacks(t *testing.T) {
+	var up ttnUplink
+	up.EndDeviceIDs.DeviceID = "raw-1"
+	up.UplinkMessage.FrmPayload = "AQID"
+	rssi := -95.0
+	up.UplinkMessage.RxMetadata = append(up.UplinkMessage.RxMetadata, struct {
+		GatewayIDs struct {
+			GatewayID string `json:"gateway_id"`
+		} `json:"gateway_ids"`
+		RSSI        *float64 `json:"rssi"`
+		ChannelRSSI *float64 `json:"channel_rssi"`
+		SNR         *float64 `json:"snr"`
+	}{ChannelRSSI: &rssi})
+
+	row := ttnRow(&up)
+	assertMetrics(t, row, map[string]interface{}{
+		"ship_id":     "raw-1",
+		"frm_payload": "AQID", // no payload formatter
+		"rssi":        -95.0,  // channel_rssi when rssi is missing
+		"gateways":    int64(1),
+	})
+	for _, k := range []string{"snr", "airtime_ms", "frequency_hz", "spreading_factor"} {
+		if _, ok := row[k]; ok {
+			t.Errorf("%s should not be set", k)
+		}
+	}
+}
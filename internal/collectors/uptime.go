@@ -1,21 +1,31 @@
 package collectors
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptrace"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // UptimeCollector checks the availability of a target URL with detailed timing metrics.
-// It returns a slice containing ONE map with numerical values only.
+// With target_url it returns a slice containing ONE map with numerical values only.
+// With targets=url1,url2 every URL becomes its own ship (or cargo with target_mode=cargo).
+//
+//	--param method=POST --param body='{"ping":true}' --param header.Content-Type=application/json
+//	--param basic_auth_user=admin --param basic_auth_pass=secret   (or bearer_token=...)
+//	--param follow_redirects=true   (or a max hop count; default: report the redirect itself)
+//	--param ip_version=6            (force IPv4 or IPv6)
+//	--param resolver=1.1.1.1:53     (default: system resolver)
 func UptimeCollector(params map[string]string) ([]map[string]interface{}, error) {
 	// 1. Validation: Ensure target exists
-	target, ok := params["target_url"]
-	if !ok || target == "" {
+	target := params["target_url"]
+	if target == "" && params["targets"] == "" {
 		return nil, fmt.Errorf("missing target_url param")
 	}
 
@@ -27,6 +37,95 @@ func UptimeCollector(params map[string]string) ([]map[string]interface{}, error)
 		}
 	}
 
+	// Optional assertions (status range, body, JSON paths, headers, latency)
+	assertions, err := newHTTPAssertions(params)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := newUptimeOptions(params, timeoutDuration)
+	if err != nil {
+		return nil, err
+	}
+	// Fresh connections every run so DNS/connect/TLS timings are always measured
+	defer opts.transport.CloseIdleConnections()
+
+	if params["targets"] != "" {
+		return probeTargets(params, "http", "http_up", func(t string) map[string]interface{} {
+			return checkURL(t, opts, assertions)
+		})
+	}
+	return []map[string]interface{}{checkURL(target, opts, assertions)}, nil
+}
+
+// uptimeOptions is the request shape shared by every target of one instance.
+type uptimeOptions struct {
+	timeout      time.Duration
+	method       string
+	body         string
+	headers      map[string]string
+	basicUser    string
+	basicPass    string
+	bearerToken  string
+	maxRedirects int // 0 = don't follow
+	transport    *http.Transport
+}
+
+func newUptimeOptions(params map[string]string, timeout time.Duration) (*uptimeOptions, error) {
+	o := &uptimeOptions{
+		timeout:     timeout,
+		method:      strings.ToUpper(params["method"]),
+		body:        params["body"],
+		headers:     make(map[string]string),
+		basicUser:   params["basic_auth_user"],
+		basicPass:   params["basic_auth_pass"],
+		bearerToken: params["bearer_token"],
+	}
+	if o.method == "" {
+		o.method = http.MethodGet
+	}
+	for k, v := range params {
+		if name, ok := strings.CutPrefix(k, "header."); ok && name != "" {
+			o.headers[name] = v
+		}
+	}
+
+	// follow_redirects=true follows up to 10 hops, a number sets the limit
+	if v := params["follow_redirects"]; v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			o.maxRedirects = max(n, 0)
+		} else if paramBool(params, "follow_redirects") {
+			o.maxRedirects = 10
+		}
+	}
+
+	network := "tcp"
+	switch params["ip_version"] {
+	case "", "any":
+	case "4":
+		network = "tcp4"
+	case "6":
+		network = "tcp6"
+	default:
+		return nil, fmt.Errorf("bad ip_version %q (use 4 or 6)", params["ip_version"])
+	}
+
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: -1}
+	if server := params["resolver"]; server != "" {
+		dialer.Resolver = customResolver(server)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+	transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	o.transport = transport
+	return o, nil
+}
+
+// checkURL performs one timed request and evaluates the assertions against it.
+func checkURL(target string, opts *uptimeOptions, assertions *httpAssertions) map[string]interface{} {
 	// 3. Timing variables for detailed metrics
 	var dnsStart, dnsDone, connectStart, connectDone, tlsStart, tlsDone, firstByteTime time.Time
 	var redirectCount int64 = 0
@@ -58,29 +157,22 @@ func UptimeCollector(params map[string]string) ([]map[string]interface{}, error)
 
 	// 5. Client Setup: Custom client with redirect counting
 	client := &http.Client{
-		Timeout: timeoutDuration,
+		Timeout:   opts.timeout,
+		Transport: opts.transport,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-		    redirectCount = int64(len(via))
-		    return http.ErrUseLastResponse 
+			redirectCount = int64(len(via))
+			// Go re-sends our headers on each hop (dropping credentials across hosts)
+			if len(via) > opts.maxRedirects {
+				return http.ErrUseLastResponse
+			}
+			return nil
 		},
 	}
 
-	// 6. Create request with trace
-	req, err := http.NewRequest("GET", target, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
-
-	// 7. Execution
-	start := time.Now()
-	resp, err := client.Do(req)
-	totalDuration := time.Since(start).Milliseconds()
-
-	// 8. Initialize result with default values (all numeric)
+	// 6. Initialize result with default values (all numeric)
 	result := map[string]interface{}{
 		"http_up":                  int64(0),
-		"http_latency_ms":          totalDuration,
+		"http_latency_ms":          int64(0),
 		"http_status_code":         int64(0),
 		"http_dns_lookup_ms":       int64(0),
 		"http_tcp_connect_ms":      int64(0),
@@ -88,10 +180,31 @@ func UptimeCollector(params map[string]string) ([]map[string]interface{}, error)
 		"http_ttfb_ms":             int64(0),
 		"http_download_ms":         int64(0),
 		"http_response_size_bytes": int64(0),
-		"http_redirect_count":      redirectCount,
+		"http_redirect_count":      int64(0),
 		"http_cache_hit":           int64(0),
+		"http_check_passed":        int64(0),
 	}
 
+	// 7. Create request with trace
+	var reqBody io.Reader
+	if opts.body != "" {
+		reqBody = strings.NewReader(opts.body)
+	}
+	req, err := http.NewRequest(opts.method, target, reqBody)
+	if err != nil {
+		result["http_request_error"] = int64(1)
+		return result
+	}
+	opts.apply(req)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	// 8. Execution
+	start := time.Now()
+	resp, err := client.Do(req)
+	totalDuration := time.Since(start).Milliseconds()
+	result["http_latency_ms"] = totalDuration
+	result["http_redirect_count"] = redirectCount
+
 	// 9. Process successful response
 	if err == nil {
 		defer resp.Body.Close()
@@ -139,9 +252,30 @@ func UptimeCollector(params map[string]string) ([]map[string]interface{}, error)
 		if cacheStatus == "HIT" || cacheStatus == "hit" || cacheStatus == "Hit" {
 			result["http_cache_hit"] = int64(1)
 		}
+
+		// A response alone isn't "healthy": a 500 or maintenance page still counts as up
+		if assertions.check(result, resp, bodyBytes, totalDuration) {
+			result["http_check_passed"] = int64(1)
+		}
 	}
 
-	return []map[string]interface{}{result}, nil
+	return result
+}
+
+// apply sets the configured headers and credentials on the first request.
+func (o *uptimeOptions) apply(req *http.Request) {
+	for name, v := range o.headers {
+		if strings.EqualFold(name, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(name, v)
+	}
+	if o.basicUser != "" {
+		req.SetBasicAuth(o.basicUser, o.basicPass)
+	} else if o.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+o.bearerToken)
+	}
 }
 
 // getCacheStatus extracts cache status from various CDN headers
@@ -0,0 +1,192 @@
+package collectors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// httpAssertions are the optional checks an uptime probe must pass before
+// we call it healthy. A bare 2xx/3xx is enough when nothing is configured.
+//
+//	--param expect_status=200-299          (ranges and lists: "200,204,300-399")
+//	--param expect_body="All systems go"
+//	--param expect_regex="version \d+\.\d+"
+//	--param max_latency_ms=800
+//	--param expect_json.status=ok          (operators: ==, !=, <, <=, >, >=)
+//	--param expect_json.queue.depth="<100"
+//	--param expect_header.Content-Type=application/json   (empty value = must exist)
+type httpAssertions struct {
+	status     [][2]int
+	body       string
+	regex      *regexp.Regexp
+	maxLatency int64
+	json       map[string]string
+	headers    map[string]string
+}
+
+func newHTTPAssertions(params map[string]string) (*httpAssertions, error) {
+	a := &httpAssertions{
+		body:       params["expect_body"],
+		maxLatency: int64(paramInt(params, "max_latency_ms", 0)),
+		json:       make(map[string]string),
+		headers:    make(map[string]string),
+	}
+
+	status := params["expect_status"]
+	if status == "" {
+		status = "200-399"
+	}
+	for _, part := range splitList(status) {
+		lo, hi, isRange := strings.Cut(part, "-")
+		if !isRange {
+			hi = lo
+		}
+		l, err1 := strconv.Atoi(strings.TrimSpace(lo))
+		h, err2 := strconv.Atoi(strings.TrimSpace(hi))
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("bad expect_status %q", part)
+		}
+		a.status = append(a.status, [2]int{l, h})
+	}
+
+	if r := params["expect_regex"]; r != "" {
+		re, err := regexp.Compile(r)
+		if err != nil {
+			return nil, fmt.Errorf("bad expect_regex: %w", err)
+		}
+		a.regex = re
+	}
+
+	for k, v := range params {
+		if path, ok := strings.CutPrefix(k, "expect_json."); ok && path != "" {
+			a.json[path] = v
+		}
+		if name, ok := strings.CutPrefix(k, "expect_header."); ok && name != "" {
+			a.headers[name] = v
+		}
+	}
+	return a, nil
+}
+
+// check evaluates every assertion, writing http_assert_* results into result,
+// and reports whether all of them passed.
+func (a *httpAssertions) check(result map[string]interface{}, resp *http.Response, body []byte, latencyMs int64) bool {
+	passed := true
+	record := func(key string, ok bool) {
+		result[key] = boolToInt(ok)
+		passed = passed && ok
+	}
+
+	statusOK := false
+	for _, r := range a.status {
+		if resp.StatusCode >= r[0] && resp.StatusCode <= r[1] {
+			statusOK = true
+			break
+		}
+	}
+	record("http_assert_status", statusOK)
+
+	if a.body != "" {
+		record("http_assert_body", strings.Contains(string(body), a.body))
+	}
+	if a.regex != nil {
+		record("http_assert_regex", a.regex.Match(body))
+	}
+	if a.maxLatency > 0 {
+		record("http_assert_latency", latencyMs <= a.maxLatency)
+	}
+
+	for name, want := range a.headers {
+		got := resp.Header.Get(name)
+		ok := got != "" && (want == "" || strings.Contains(got, want))
+		record("http_assert_header."+name, ok)
+	}
+
+	if len(a.json) > 0 {
+		var doc interface{}
+		parsed := json.Unmarshal(body, &doc) == nil
+		for path, expr := range a.json {
+			ok := false
+			if parsed {
+				if v, found := jsonPath(doc, path); found {
+					ok = compareJSONValue(v, expr)
+				}
+			}
+			record("http_assert_json."+path, ok)
+		}
+	}
+
+	return passed
+}
+
+// jsonPath walks "a.b.0.c" through objects and arrays.
+func jsonPath(doc interface{}, path string) (interface{}, bool) {
+	cur := doc
+	for _, key := range strings.Split(path, ".") {
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			v, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			cur = v
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			cur = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// compareJSONValue applies "<op><value>" (op defaults to ==). Numbers compare
+// numerically, everything else as strings.
+func compareJSONValue(v interface{}, expr string) bool {
+	op, want := "==", expr
+	for _, candidate := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if rest, ok := strings.CutPrefix(expr, candidate); ok {
+			op, want = candidate, strings.TrimSpace(rest)
+			break
+		}
+	}
+
+	got := fmt.Sprint(v)
+	if v == nil {
+		got = "null"
+	}
+
+	if gf, ok := v.(float64); ok {
+		if wf, err := strconv.ParseFloat(want, 64); err == nil {
+			switch op {
+			case "==":
+				return gf == wf
+			case "!=":
+				return gf != wf
+			case "<":
+				return gf < wf
+			case "<=":
+				return gf <= wf
+			case ">":
+				return gf > wf
+			case ">=":
+				return gf >= wf
+			}
+		}
+	}
+
+	switch op {
+	case "==":
+		return got == want
+	case "!=":
+		return got != want
+	}
+	return false
+}